@@ -4,11 +4,13 @@ import (
 	"bytes"
 	"fmt"
 	"log"
+	"net/http"
 	"os"
 	"strings"
+	"time"
+	_ "time/tzdata"
 
 	dto "github.com/prometheus/client_model/go"
-	"github.com/prometheus/common/expfmt"
 	v2 "github.com/sensu/sensu-go/api/core/v2"
 	"github.com/sensu/sensu-plugin-sdk/sensu"
 )
@@ -18,10 +20,53 @@ type Config struct {
 	sensu.PluginConfig
 	Sum                    bool
 	SumoLogicCompat        bool
+	SumoLogicFieldNames    bool
 	IncludeInterfaces      []string
 	ExcludeInterfaces      []string
 	StateFile              string
 	MaxRateIntervalSeconds int64
+	DeltaMetrics           bool
+	LifetimeCounters       bool
+	TrafficAccounting      bool
+	BillingPercentile      []string
+	AnomalyZScore          float64
+	AnomalyAlpha           float64
+	AnomalyWarmup          int64
+	AlertRaiseAfter        int
+	AlertClearAfter        int
+	IdleDetection          bool
+	IdleThreshold          int64
+	TrafficTimezone        string
+	TrafficQuotas          []string
+	TrafficQuotaWarning    float64
+	TrafficQuotaCritical   float64
+	MaxInterfaces          int
+	Naming                 string
+	MetricPrefix           string
+	Labels                 map[string]string
+	EntityLabels           []string
+	AddEntityMetadata      bool
+	IncludeMetrics         []string
+	ExcludeMetrics         []string
+	RateUnit               string
+	OutputFormat           string
+	Warning                map[string]string
+	Critical               map[string]string
+	GraphiteTemplate       string
+	Listen                 string
+	TextfileDir            string
+	PushgatewayURL         string
+	RemoteWriteURL         string
+	PushJob                string
+	PushInstance           string
+	PushUsername           string
+	PushPassword           string
+	PushBearerToken        string
+	PushRetries            int
+	PushTimeout            int64
+	OTLPEndpoint           string
+	OTLPHeaders            map[string]string
+	StatsDAddress          string
 }
 
 var (
@@ -35,6 +80,29 @@ var (
 		ExcludeInterfaces:      make([]string, 0),
 		StateFile:              "",
 		MaxRateIntervalSeconds: 60,
+		MaxInterfaces:          0,
+		TrafficTimezone:        "Local",
+		TrafficQuotas:          make([]string, 0),
+		TrafficQuotaWarning:    80,
+		TrafficQuotaCritical:   100,
+		BillingPercentile:      make([]string, 0),
+		Naming:                 namingDefault,
+		MetricPrefix:           "",
+		Labels:                 map[string]string{},
+		EntityLabels:           make([]string, 0),
+		IncludeMetrics:         make([]string, 0),
+		ExcludeMetrics:         make([]string, 0),
+		RateUnit:               rateUnitBytes,
+		OutputFormat:           outputFormatPrometheus,
+		Warning:                map[string]string{},
+		Critical:               map[string]string{},
+		GraphiteTemplate:       defaultGraphiteTemplate,
+		Listen:                 defaultListen,
+		TextfileDir:            "",
+		PushJob:                defaultPushJob,
+		PushRetries:            defaultPushRetries,
+		PushTimeout:            defaultPushTimeout,
+		OTLPHeaders:            map[string]string{},
 	}
 
 	options = []*sensu.PluginConfigOption{
@@ -46,6 +114,14 @@ var (
 			Default:   false,
 			Usage:     "Add Sumo Logic compatible metrics with w/ \"host_net\" family",
 			Value:     &plugin.SumoLogicCompat,
+		}, {
+			Path:      "sumologic-field-names",
+			Env:       "NETWORK_INTERFACE_CHECKS_SUMOLOGIC_FIELD_NAMES",
+			Argument:  "sumologic-field-names",
+			Shorthand: "",
+			Default:   false,
+			Usage:     "Name the \"host_net\" fields after the Sumo Logic Host Metrics source and add the rates and sums, enables --sumologic-compat",
+			Value:     &plugin.SumoLogicFieldNames,
 		}, {
 			Path:      "sum",
 			Env:       "NETWORK_INTERFACE_CHECKS_SUM",
@@ -86,6 +162,344 @@ var (
 			Default:   int64(60),
 			Usage:     "Maximum number of seconds since last measurement that triggers a rate calculation. 0 for no maximum.",
 			Value:     &plugin.MaxRateIntervalSeconds,
+		}, {
+			Path:      "delta-metrics",
+			Env:       "NETWORK_INTERFACE_CHECKS_DELTA_METRICS",
+			Argument:  "delta-metrics",
+			Shorthand: "",
+			Default:   false,
+			Usage:     "Add *_delta gauges with the increase of each counter since the previous run, and the delta_interval_seconds gauges",
+			Value:     &plugin.DeltaMetrics,
+		}, {
+			Path:      "lifetime-counters",
+			Env:       "NETWORK_INTERFACE_CHECKS_LIFETIME_COUNTERS",
+			Argument:  "lifetime-counters",
+			Shorthand: "",
+			Default:   false,
+			Usage:     "Add *_lifetime counters accumulated in the state file across counter resets and reboots",
+			Value:     &plugin.LifetimeCounters,
+		}, {
+			Path:      "traffic-accounting",
+			Env:       "NETWORK_INTERFACE_CHECKS_TRAFFIC_ACCOUNTING",
+			Argument:  "traffic-accounting",
+			Shorthand: "",
+			Default:   false,
+			Usage:     "Add traffic_today_bytes and traffic_month_bytes gauges accounted in the state file",
+			Value:     &plugin.TrafficAccounting,
+		}, {
+			Path:      "traffic-timezone",
+			Env:       "NETWORK_INTERFACE_CHECKS_TRAFFIC_TIMEZONE",
+			Argument:  "traffic-timezone",
+			Shorthand: "",
+			Default:   "Local",
+			Usage:     "Time zone of the day and month traffic rollover, Local for the host time zone or e.g. UTC, Europe/Paris",
+			Value:     &plugin.TrafficTimezone,
+		}, {
+			Path:      "traffic-quota",
+			Env:       "NETWORK_INTERFACE_CHECKS_TRAFFIC_QUOTA",
+			Argument:  "traffic-quota",
+			Shorthand: "",
+			Default:   []string{},
+			Usage:     "Comma-delimited traffic quotas in interface=amount/period format, e.g. eth0=10TB/month,eth0=500GB/day",
+			Value:     &plugin.TrafficQuotas,
+		}, {
+			Path:      "traffic-quota-warning",
+			Env:       "NETWORK_INTERFACE_CHECKS_TRAFFIC_QUOTA_WARNING",
+			Argument:  "traffic-quota-warning",
+			Shorthand: "",
+			Default:   float64(80),
+			Usage:     "Percentage of a traffic quota that triggers a warning",
+			Value:     &plugin.TrafficQuotaWarning,
+		}, {
+			Path:      "traffic-quota-critical",
+			Env:       "NETWORK_INTERFACE_CHECKS_TRAFFIC_QUOTA_CRITICAL",
+			Argument:  "traffic-quota-critical",
+			Shorthand: "",
+			Default:   float64(100),
+			Usage:     "Percentage of a traffic quota that triggers a critical",
+			Value:     &plugin.TrafficQuotaCritical,
+		}, {
+			Path:      "billing-percentile",
+			Env:       "NETWORK_INTERFACE_CHECKS_BILLING_PERCENTILE",
+			Argument:  "billing-percentile",
+			Shorthand: "",
+			Default:   []string{},
+			Usage:     "Comma-delimited interfaces, \"all\" for the --sum totals, keeping the 5 minute samples of the month in the state file for the bytes_recv_rate_p95 and bytes_sent_rate_p95 gauges",
+			Value:     &plugin.BillingPercentile,
+		}, {
+			Path:      "anomaly-zscore",
+			Env:       "NETWORK_INTERFACE_CHECKS_ANOMALY_ZSCORE",
+			Argument:  "anomaly-zscore",
+			Shorthand: "",
+			Default:   float64(0),
+			Usage:     "Number of standard deviations from its baseline in the state file that makes a rate a warning, and adds the *_rate_zscore gauges. 0 to disable.",
+			Value:     &plugin.AnomalyZScore,
+		}, {
+			Path:      "anomaly-alpha",
+			Env:       "NETWORK_INTERFACE_CHECKS_ANOMALY_ALPHA",
+			Argument:  "anomaly-alpha",
+			Shorthand: "",
+			Default:   float64(0.05),
+			Usage:     "Weight of the last rate in the exponentially weighted baseline of the rates, between 0 and 1",
+			Value:     &plugin.AnomalyAlpha,
+		}, {
+			Path:      "anomaly-warmup",
+			Env:       "NETWORK_INTERFACE_CHECKS_ANOMALY_WARMUP",
+			Argument:  "anomaly-warmup",
+			Shorthand: "",
+			Default:   int64(30),
+			Usage:     "Number of rates in the baseline of a rate before its z-score is computed",
+			Value:     &plugin.AnomalyWarmup,
+		}, {
+			Path:      "alert-raise-after",
+			Env:       "NETWORK_INTERFACE_CHECKS_ALERT_RAISE_AFTER",
+			Argument:  "alert-raise-after",
+			Shorthand: "",
+			Default:   1,
+			Usage:     "Number of consecutive runs breaching a threshold, quota, baseline or idle threshold on an interface before its alert is raised",
+			Value:     &plugin.AlertRaiseAfter,
+		}, {
+			Path:      "alert-clear-after",
+			Env:       "NETWORK_INTERFACE_CHECKS_ALERT_CLEAR_AFTER",
+			Argument:  "alert-clear-after",
+			Shorthand: "",
+			Default:   1,
+			Usage:     "Number of consecutive runs without breach on an interface before its raised alert is cleared",
+			Value:     &plugin.AlertClearAfter,
+		}, {
+			Path:      "idle-detection",
+			Env:       "NETWORK_INTERFACE_CHECKS_IDLE_DETECTION",
+			Argument:  "idle-detection",
+			Shorthand: "",
+			Default:   false,
+			Usage:     "Add seconds_since_last_rx and seconds_since_last_tx gauges from the last change of the packets counters in the state file",
+			Value:     &plugin.IdleDetection,
+		}, {
+			Path:      "idle-threshold",
+			Env:       "NETWORK_INTERFACE_CHECKS_IDLE_THRESHOLD",
+			Argument:  "idle-threshold",
+			Shorthand: "",
+			Default:   int64(0),
+			Usage:     "Number of seconds without packet received or sent that makes an interface a warning, enables --idle-detection. 0 for no alert.",
+			Value:     &plugin.IdleThreshold,
+		}, {
+			Path:      "max-interfaces",
+			Env:       "NETWORK_INTERFACE_CHECKS_MAX_INTERFACES",
+			Argument:  "max-interfaces",
+			Shorthand: "",
+			Default:   0,
+			Usage:     "Maximum number of interfaces to emit, busiest first. Remaining interfaces are folded into \"interface=other\". 0 for no maximum.",
+			Value:     &plugin.MaxInterfaces,
+		}, {
+			Path:      "naming",
+			Env:       "NETWORK_INTERFACE_CHECKS_NAMING",
+			Argument:  "naming",
+			Shorthand: "",
+			Default:   namingDefault,
+			Usage:     fmt.Sprintf("Metric naming mode, one of: %s", strings.Join(namingModes, ", ")),
+			Value:     &plugin.Naming,
+		}, {
+			Path:      "metric-prefix",
+			Env:       "NETWORK_INTERFACE_CHECKS_METRIC_PREFIX",
+			Argument:  "metric-prefix",
+			Shorthand: "",
+			Default:   "",
+			Usage:     "Prefix added to every metric name, e.g. \"sensu_net_\"",
+			Value:     &plugin.MetricPrefix,
+		}, {
+			Path:      "label",
+			Env:       "NETWORK_INTERFACE_CHECKS_LABEL",
+			Argument:  "label",
+			Shorthand: "l",
+			Default:   map[string]string{},
+			Usage:     "Additional label added to every metric in key=value format, can be repeated",
+			Value:     &plugin.Labels,
+		}, {
+			Path:      "entity-labels",
+			Env:       "NETWORK_INTERFACE_CHECKS_ENTITY_LABELS",
+			Argument:  "entity-labels",
+			Shorthand: "",
+			Default:   []string{},
+			Usage:     "Comma-delimited string of Sensu entity labels added to every metric, requires the event on stdin",
+			Value:     &plugin.EntityLabels,
+		}, {
+			Path:      "add-entity-metadata",
+			Env:       "NETWORK_INTERFACE_CHECKS_ADD_ENTITY_METADATA",
+			Argument:  "add-entity-metadata",
+			Shorthand: "",
+			Default:   false,
+			Usage:     "Add \"entity\" and \"namespace\" labels from the Sensu entity to every metric, requires the event on stdin",
+			Value:     &plugin.AddEntityMetadata,
+		}, {
+			Path:      "include-metrics",
+			Env:       "NETWORK_INTERFACE_CHECKS_INCLUDE_METRICS",
+			Argument:  "include-metrics",
+			Shorthand: "",
+			Default:   []string{},
+			Usage:     "Comma-delimited string of metric names to include, as globs or as /regular expressions/",
+			Value:     &plugin.IncludeMetrics,
+		}, {
+			Path:      "exclude-metrics",
+			Env:       "NETWORK_INTERFACE_CHECKS_EXCLUDE_METRICS",
+			Argument:  "exclude-metrics",
+			Shorthand: "",
+			Default:   []string{},
+			Usage:     "Comma-delimited string of metric names to exclude, as globs or as /regular expressions/",
+			Value:     &plugin.ExcludeMetrics,
+		}, {
+			Path:      "rate-unit",
+			Env:       "NETWORK_INTERFACE_CHECKS_RATE_UNIT",
+			Argument:  "rate-unit",
+			Shorthand: "",
+			Default:   rateUnitBytes,
+			Usage:     fmt.Sprintf("Unit of the bytes_recv_rate and bytes_sent_rate metrics, one of: %s", strings.Join(rateUnitNames, ", ")),
+			Value:     &plugin.RateUnit,
+		}, {
+			Path:      "output-format",
+			Env:       "NETWORK_INTERFACE_CHECKS_OUTPUT_FORMAT",
+			Argument:  "output-format",
+			Shorthand: "o",
+			Default:   outputFormatPrometheus,
+			Usage:     fmt.Sprintf("Output format, one of: %s", strings.Join(outputFormats, ", ")),
+			Value:     &plugin.OutputFormat,
+		}, {
+			Path:      "warning",
+			Env:       "NETWORK_INTERFACE_CHECKS_WARNING",
+			Argument:  "warning",
+			Shorthand: "w",
+			Default:   map[string]string{},
+			Usage:     "Warning threshold in metric=value format, e.g. bytes_recv_rate=1000000, can be repeated",
+			Value:     &plugin.Warning,
+		}, {
+			Path:      "critical",
+			Env:       "NETWORK_INTERFACE_CHECKS_CRITICAL",
+			Argument:  "critical",
+			Shorthand: "c",
+			Default:   map[string]string{},
+			Usage:     "Critical threshold in metric=value format, e.g. bytes_recv_rate=5000000, can be repeated",
+			Value:     &plugin.Critical,
+		}, {
+			Path:      "graphite-template",
+			Env:       "NETWORK_INTERFACE_CHECKS_GRAPHITE_TEMPLATE",
+			Argument:  "graphite-template",
+			Shorthand: "",
+			Default:   defaultGraphiteTemplate,
+			Usage:     "Metric path template of the graphite_plaintext output format, {host}, {metric} and {<label>} are replaced",
+			Value:     &plugin.GraphiteTemplate,
+		}, {
+			Path:      "listen",
+			Env:       "NETWORK_INTERFACE_CHECKS_LISTEN",
+			Argument:  "listen",
+			Shorthand: "",
+			Default:   defaultListen,
+			Usage:     "Address the serve subcommand exposes /metrics and /healthz on",
+			Value:     &plugin.Listen,
+		}, {
+			Path:      "textfile-dir",
+			Env:       "NETWORK_INTERFACE_CHECKS_TEXTFILE_DIR",
+			Argument:  "textfile-dir",
+			Shorthand: "",
+			Default:   "",
+			Usage:     "node_exporter textfile collector directory the metrics are also written to, as " + textfileName,
+			Value:     &plugin.TextfileDir,
+		}, {
+			Path:      "pushgateway-url",
+			Env:       "NETWORK_INTERFACE_CHECKS_PUSHGATEWAY_URL",
+			Argument:  "pushgateway-url",
+			Shorthand: "",
+			Default:   "",
+			Usage:     "Pushgateway URL the metrics are also pushed to, grouped by --push-job and --push-instance",
+			Value:     &plugin.PushgatewayURL,
+		}, {
+			Path:      "remote-write-url",
+			Env:       "NETWORK_INTERFACE_CHECKS_REMOTE_WRITE_URL",
+			Argument:  "remote-write-url",
+			Shorthand: "",
+			Default:   "",
+			Usage:     "Prometheus remote-write URL the metrics are also sent to",
+			Value:     &plugin.RemoteWriteURL,
+		}, {
+			Path:      "push-job",
+			Env:       "NETWORK_INTERFACE_CHECKS_PUSH_JOB",
+			Argument:  "push-job",
+			Shorthand: "",
+			Default:   defaultPushJob,
+			Usage:     "job label of the pushed metrics",
+			Value:     &plugin.PushJob,
+		}, {
+			Path:      "push-instance",
+			Env:       "NETWORK_INTERFACE_CHECKS_PUSH_INSTANCE",
+			Argument:  "push-instance",
+			Shorthand: "",
+			Default:   "",
+			Usage:     "instance label of the pushed metrics, the Sensu entity name or the host name if empty",
+			Value:     &plugin.PushInstance,
+		}, {
+			Path:      "push-username",
+			Env:       "NETWORK_INTERFACE_CHECKS_PUSH_USERNAME",
+			Argument:  "push-username",
+			Shorthand: "",
+			Default:   "",
+			Usage:     "Basic authentication username of the push endpoints",
+			Value:     &plugin.PushUsername,
+		}, {
+			Path:      "push-password",
+			Env:       "NETWORK_INTERFACE_CHECKS_PUSH_PASSWORD",
+			Argument:  "push-password",
+			Shorthand: "",
+			Default:   "",
+			Usage:     "Basic authentication password of the push endpoints",
+			Value:     &plugin.PushPassword,
+			Secret:    true,
+		}, {
+			Path:      "push-bearer-token",
+			Env:       "NETWORK_INTERFACE_CHECKS_PUSH_BEARER_TOKEN",
+			Argument:  "push-bearer-token",
+			Shorthand: "",
+			Default:   "",
+			Usage:     "Bearer token of the push endpoints",
+			Value:     &plugin.PushBearerToken,
+			Secret:    true,
+		}, {
+			Path:      "push-retries",
+			Env:       "NETWORK_INTERFACE_CHECKS_PUSH_RETRIES",
+			Argument:  "push-retries",
+			Shorthand: "",
+			Default:   defaultPushRetries,
+			Usage:     "Number of retries of a failed push, with exponential backoff from 1 second",
+			Value:     &plugin.PushRetries,
+		}, {
+			Path:      "push-timeout",
+			Env:       "NETWORK_INTERFACE_CHECKS_PUSH_TIMEOUT",
+			Argument:  "push-timeout",
+			Shorthand: "",
+			Default:   int64(defaultPushTimeout),
+			Usage:     "Number of seconds all the pushes and their retries must complete within, keep it under the check timeout",
+			Value:     &plugin.PushTimeout,
+		}, {
+			Path:      "otlp-endpoint",
+			Env:       "NETWORK_INTERFACE_CHECKS_OTLP_ENDPOINT",
+			Argument:  "otlp-endpoint",
+			Shorthand: "",
+			Default:   "",
+			Usage:     "OTLP/HTTP endpoint the metrics are also exported to, e.g. http://localhost:4318",
+			Value:     &plugin.OTLPEndpoint,
+		}, {
+			Path:      "otlp-header",
+			Env:       "NETWORK_INTERFACE_CHECKS_OTLP_HEADER",
+			Argument:  "otlp-header",
+			Shorthand: "",
+			Default:   map[string]string{},
+			Usage:     "Header sent to the OTLP endpoint in key=value format, can be repeated",
+			Value:     &plugin.OTLPHeaders,
+		}, {
+			Path:      "statsd-address",
+			Env:       "NETWORK_INTERFACE_CHECKS_STATSD_ADDRESS",
+			Argument:  "statsd-address",
+			Shorthand: "",
+			Default:   "",
+			Usage:     "DogStatsD UDP address the metrics are also sent to, e.g. 127.0.0.1:8125",
+			Value:     &plugin.StatsDAddress,
 		},
 	}
 )
@@ -103,11 +517,30 @@ func main() {
 		useStdin = true
 	}
 
+	if args, ok := serveArgs(os.Args); ok {
+		os.Args = args
+		newServeCheck().Execute()
+		return
+	}
+
 	check := sensu.NewGoCheck(&plugin.PluginConfig, options, checkArgs, executeCheck, useStdin)
 	check.Execute()
 }
 
-func checkArgs(_ *v2.Event) (int, error) {
+// checkArgs validates the flags of the check, which keeps the alert state from one run to the next in --state-file
+// only.
+func checkArgs(event *v2.Event) (int, error) {
+	if status, err := validateArgs(event); err != nil {
+		return status, err
+	}
+	if plugin.StateFile == "" && (plugin.AlertRaiseAfter > 1 || plugin.AlertClearAfter > 1) {
+		return sensu.CheckStateCritical, fmt.Errorf("--alert-raise-after and --alert-clear-after require --state-file")
+	}
+	return sensu.CheckStateOK, nil
+}
+
+// validateArgs validates the flags shared by the check and the serve subcommand.
+func validateArgs(_ *v2.Event) (int, error) {
 	for i, include := range plugin.IncludeInterfaces {
 		plugin.IncludeInterfaces[i] = strings.TrimSpace(include)
 	}
@@ -127,46 +560,293 @@ func checkArgs(_ *v2.Event) (int, error) {
 		return sensu.CheckStateCritical, fmt.Errorf("--max-rate-interval must be 0 or a positive value")
 	}
 
+	if plugin.MaxInterfaces < 0 {
+		return sensu.CheckStateCritical, fmt.Errorf("--max-interfaces must be 0 or a positive value")
+	}
+
+	if plugin.Naming == "" {
+		plugin.Naming = namingDefault
+	}
+	if !validNaming(plugin.Naming) {
+		return sensu.CheckStateCritical, fmt.Errorf("--naming must be one of: %s", strings.Join(namingModes, ", "))
+	}
+
+	if plugin.RateUnit == "" {
+		plugin.RateUnit = rateUnitBytes
+	}
+	if !validRateUnit(plugin.RateUnit) {
+		return sensu.CheckStateCritical, fmt.Errorf("--rate-unit must be one of: %s", strings.Join(rateUnitNames, ", "))
+	}
+
+	if plugin.OutputFormat == "" {
+		plugin.OutputFormat = outputFormatPrometheus
+	}
+	if !validOutputFormat(plugin.OutputFormat) {
+		return sensu.CheckStateCritical, fmt.Errorf("--output-format must be one of: %s", strings.Join(outputFormats, ", "))
+	}
+
+	if plugin.GraphiteTemplate == "" {
+		plugin.GraphiteTemplate = defaultGraphiteTemplate
+	}
+	if err := validateGraphiteTemplate(plugin.GraphiteTemplate); err != nil {
+		return sensu.CheckStateCritical, err
+	}
+
+	if plugin.Listen == "" {
+		plugin.Listen = defaultListen
+	}
+
+	if plugin.TextfileDir != "" {
+		if err := validateTextfileDir(plugin.TextfileDir); err != nil {
+			return sensu.CheckStateCritical, err
+		}
+	}
+
+	if plugin.PushgatewayURL != "" {
+		if err := validatePushURL("pushgateway-url", plugin.PushgatewayURL); err != nil {
+			return sensu.CheckStateCritical, err
+		}
+	}
+	if plugin.RemoteWriteURL != "" {
+		if err := validatePushURL("remote-write-url", plugin.RemoteWriteURL); err != nil {
+			return sensu.CheckStateCritical, err
+		}
+	}
+	if plugin.OTLPEndpoint != "" {
+		if err := validatePushURL("otlp-endpoint", plugin.OTLPEndpoint); err != nil {
+			return sensu.CheckStateCritical, err
+		}
+	}
+	if err := validateOTLPHeaders(plugin.OTLPHeaders); err != nil {
+		return sensu.CheckStateCritical, err
+	}
+	if plugin.StatsDAddress != "" {
+		if err := validateStatsDAddress(plugin.StatsDAddress); err != nil {
+			return sensu.CheckStateCritical, err
+		}
+	}
+	if plugin.PushJob == "" {
+		plugin.PushJob = defaultPushJob
+	}
+	if plugin.PushUsername != "" && plugin.PushBearerToken != "" {
+		return sensu.CheckStateCritical, fmt.Errorf("only one of --push-username or --push-bearer-token should be specified")
+	}
+	if plugin.PushRetries < 0 {
+		return sensu.CheckStateCritical, fmt.Errorf("--push-retries must be 0 or a positive value")
+	}
+	if plugin.PushTimeout == 0 {
+		plugin.PushTimeout = defaultPushTimeout
+	}
+	if plugin.PushTimeout < 0 {
+		return sensu.CheckStateCritical, fmt.Errorf("--push-timeout must be a positive value")
+	}
+
+	if _, err := parseThresholds(plugin.Warning, plugin.Critical); err != nil {
+		return sensu.CheckStateCritical, err
+	}
+	for _, values := range []map[string]string{plugin.Warning, plugin.Critical} {
+		for name := range values {
+			if strings.HasSuffix(name, deltaFamilySuffix) && !plugin.DeltaMetrics {
+				return sensu.CheckStateCritical, fmt.Errorf("the %s threshold requires --delta-metrics", name)
+			}
+		}
+	}
+
+	if plugin.TrafficTimezone == "" {
+		plugin.TrafficTimezone = "Local"
+	}
+	if _, err := time.LoadLocation(plugin.TrafficTimezone); err != nil {
+		return sensu.CheckStateCritical, fmt.Errorf("invalid --traffic-timezone %q: %v", plugin.TrafficTimezone, err)
+	}
+	if _, err := parseTrafficQuotas(plugin.TrafficQuotas); err != nil {
+		return sensu.CheckStateCritical, err
+	}
+	if plugin.TrafficQuotaWarning == 0 {
+		plugin.TrafficQuotaWarning = 80
+	}
+	if plugin.TrafficQuotaCritical == 0 {
+		plugin.TrafficQuotaCritical = 100
+	}
+	if plugin.TrafficQuotaWarning < 0 || plugin.TrafficQuotaCritical < 0 {
+		return sensu.CheckStateCritical, fmt.Errorf("--traffic-quota-warning and --traffic-quota-critical must be positive values")
+	}
+	if plugin.TrafficQuotaWarning > plugin.TrafficQuotaCritical {
+		return sensu.CheckStateCritical, fmt.Errorf("--traffic-quota-warning must not be greater than --traffic-quota-critical")
+	}
+
+	if plugin.AnomalyAlpha == 0 {
+		plugin.AnomalyAlpha = 0.05
+	}
+	if plugin.AnomalyAlpha < 0 || plugin.AnomalyAlpha > 1 {
+		return sensu.CheckStateCritical, fmt.Errorf("--anomaly-alpha must be between 0 and 1")
+	}
+	if plugin.AnomalyZScore < 0 || plugin.AnomalyWarmup < 0 {
+		return sensu.CheckStateCritical, fmt.Errorf("--anomaly-zscore and --anomaly-warmup must be 0 or positive values")
+	}
+	if plugin.AlertRaiseAfter == 0 {
+		plugin.AlertRaiseAfter = 1
+	}
+	if plugin.AlertClearAfter == 0 {
+		plugin.AlertClearAfter = 1
+	}
+	if plugin.AlertRaiseAfter < 0 || plugin.AlertClearAfter < 0 {
+		return sensu.CheckStateCritical, fmt.Errorf("--alert-raise-after and --alert-clear-after must be positive values")
+	}
+	if plugin.IdleThreshold < 0 {
+		return sensu.CheckStateCritical, fmt.Errorf("--idle-threshold must be 0 or a positive value")
+	}
+
+	if !validMetricPrefix(plugin.MetricPrefix) {
+		return sensu.CheckStateCritical, fmt.Errorf("invalid --metric-prefix %q", plugin.MetricPrefix)
+	}
+
+	if err := validateLabels(plugin.Labels); err != nil {
+		return sensu.CheckStateCritical, fmt.Errorf("invalid --label: %v", err)
+	}
+
+	for i, label := range plugin.EntityLabels {
+		plugin.EntityLabels[i] = strings.TrimSpace(label)
+	}
+	if err := validateEntityLabels(plugin.EntityLabels); err != nil {
+		return sensu.CheckStateCritical, fmt.Errorf("invalid --entity-labels: %v", err)
+	}
+
+	for i, include := range plugin.IncludeMetrics {
+		plugin.IncludeMetrics[i] = strings.TrimSpace(include)
+	}
+	for i, exclude := range plugin.ExcludeMetrics {
+		plugin.ExcludeMetrics[i] = strings.TrimSpace(exclude)
+	}
+	if _, err := NewMetricFilter(plugin.IncludeMetrics, plugin.ExcludeMetrics); err != nil {
+		return sensu.CheckStateCritical, err
+	}
+
 	return sensu.CheckStateOK, nil
 }
 
-func collectMetrics() ([]*dto.MetricFamily, error) {
-	collector, err := NewCollector(plugin.IncludeInterfaces, plugin.ExcludeInterfaces, plugin.Sum, plugin.SumoLogicCompat, plugin.StateFile,
-		plugin.MaxRateIntervalSeconds)
-	if err != nil {
-		return nil, err
+func newMetricCollector(event *v2.Event) (*MetricCollector, error) {
+	extraLabels := entityLabels(event, plugin.AddEntityMetadata, plugin.EntityLabels)
+	for name, value := range plugin.Labels {
+		extraLabels[name] = value
 	}
 
-	return collector.Collect(GetNetStats)
+	return NewCollector(CollectorOptions{
+		Includes:               plugin.IncludeInterfaces,
+		Excludes:               plugin.ExcludeInterfaces,
+		Sum:                    plugin.Sum,
+		SumoLogic:              plugin.SumoLogicCompat,
+		SumoLogicFieldNames:    plugin.SumoLogicFieldNames,
+		StateFile:              plugin.StateFile,
+		MaxRateIntervalSeconds: plugin.MaxRateIntervalSeconds,
+		MaxInterfaces:          plugin.MaxInterfaces,
+		Naming:                 plugin.Naming,
+		MetricPrefix:           plugin.MetricPrefix,
+		ExtraLabels:            extraLabels,
+		IncludeMetrics:         plugin.IncludeMetrics,
+		ExcludeMetrics:         plugin.ExcludeMetrics,
+		RateUnit:               plugin.RateUnit,
+		Warning:                plugin.Warning,
+		Critical:               plugin.Critical,
+		DeltaMetrics:           plugin.DeltaMetrics,
+		LifetimeCounters:       plugin.LifetimeCounters,
+		TrafficAccounting:      plugin.TrafficAccounting,
+		TrafficTimezone:        plugin.TrafficTimezone,
+		TrafficQuotas:          plugin.TrafficQuotas,
+		TrafficQuotaWarning:    plugin.TrafficQuotaWarning,
+		TrafficQuotaCritical:   plugin.TrafficQuotaCritical,
+		BillingPercentile:      plugin.BillingPercentile,
+		AnomalyZScore:          plugin.AnomalyZScore,
+		AnomalyAlpha:           plugin.AnomalyAlpha,
+		AnomalyWarmup:          plugin.AnomalyWarmup,
+		AlertRaiseAfter:        plugin.AlertRaiseAfter,
+		AlertClearAfter:        plugin.AlertClearAfter,
+		IdleDetection:          plugin.IdleDetection,
+		IdleThreshold:          plugin.IdleThreshold,
+		InterfaceUp:            isInterfaceUp,
+		BootID:                 getBootID(),
+	})
 }
 
-func executeCheck(_ *v2.Event) (int, error) {
-	err := generateMetrics()
+func executeCheck(event *v2.Event) (int, error) {
+	status, err := generateMetrics(event)
 	if err != nil {
 		fmt.Printf("Error executing %s: %v\n", plugin.Name, err)
 		return sensu.CheckStateCritical, nil
 	}
-	return sensu.CheckStateOK, nil
+	return status, nil
 }
 
-func generateMetrics() error {
-	families, err := collectMetrics()
+func generateMetrics(event *v2.Event) (int, error) {
+	collector, err := newMetricCollector(event)
 	if err != nil {
-		return err
+		return sensu.CheckStateCritical, err
+	}
+
+	families, err := collector.Collect(GetNetStats)
+	if err != nil {
+		return sensu.CheckStateCritical, err
 	}
 
 	var buf bytes.Buffer
-	for _, family := range families {
-		buf.Reset()
-		encoder := expfmt.NewEncoder(&buf, expfmt.FmtText)
-		err = encoder.Encode(family)
-		if err != nil {
-			return err
+	err = writeMetrics(&buf, plugin.OutputFormat, plugin.Name, plugin.GraphiteTemplate, families, collector, event)
+	if err != nil {
+		return sensu.CheckStateCritical, err
+	}
+	fmt.Print(buf.String())
+
+	if plugin.TextfileDir != "" {
+		if err := writeTextfile(plugin.TextfileDir, families, time.Now()); err != nil {
+			return sensu.CheckStateCritical, err
 		}
+	}
+
+	if err := pushMetrics(families, collector, event); err != nil {
+		return sensu.CheckStateCritical, err
+	}
+
+	if plugin.StatsDAddress != "" {
+		if err := sendDogStatsD(plugin.StatsDAddress, families, collector.deltas); err != nil {
+			return sensu.CheckStateCritical, err
+		}
+	}
+
+	return collector.Status(), nil
+}
 
-		fmt.Print(buf.String())
+// pushMetrics pushes the families to the Pushgateway, remote-write and OTLP endpoints, when configured.
+func pushMetrics(families []*dto.MetricFamily, collector *MetricCollector, event *v2.Event) error {
+	if plugin.PushgatewayURL == "" && plugin.RemoteWriteURL == "" && plugin.OTLPEndpoint == "" {
+		return nil
 	}
 
+	p := &pusher{
+		client:      &http.Client{Timeout: 10 * time.Second},
+		username:    plugin.PushUsername,
+		password:    plugin.PushPassword,
+		bearerToken: plugin.PushBearerToken,
+		retries:     plugin.PushRetries,
+		deadline:    time.Now().Add(time.Duration(plugin.PushTimeout) * time.Second),
+	}
+	instance := plugin.PushInstance
+	if instance == "" {
+		instance = hostName(event)
+	}
+
+	if plugin.PushgatewayURL != "" {
+		if err := p.pushToGateway(plugin.PushgatewayURL, plugin.PushJob, instance, families); err != nil {
+			return err
+		}
+	}
+	if plugin.RemoteWriteURL != "" {
+		if err := p.pushRemoteWrite(plugin.RemoteWriteURL, plugin.PushJob, instance, families, time.Now().UnixMilli()); err != nil {
+			return err
+		}
+	}
+	if plugin.OTLPEndpoint != "" {
+		if err := p.exportOTLP(plugin.OTLPEndpoint, plugin.OTLPHeaders, families, collector.createdMS, event); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -3,6 +3,8 @@ package main
 import (
 	"github.com/sensu/sensu-plugin-sdk/sensu"
 	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
 	"testing"
 )
 
@@ -12,6 +14,29 @@ func TestCheckArgs(t *testing.T) {
 		includesIn        []string
 		excludesIn        []string
 		maxRateIntervalIn int64
+		maxInterfacesIn   int
+		namingIn          string
+		metricPrefixIn    string
+		labelsIn          map[string]string
+		includeMetricsIn  []string
+		rateUnitIn        string
+		outputFormatIn    string
+		warningIn         map[string]string
+		graphiteTemplate  string
+		textfileDirIn     string
+		pushgatewayURLIn  string
+		pushUsernameIn    string
+		pushBearerIn      string
+		trafficTimezoneIn string
+		trafficQuotasIn   []string
+		quotaWarningIn    float64
+		quotaCriticalIn   float64
+		anomalyAlphaIn    float64
+		raiseAfterIn      int
+		clearAfterIn      int
+		stateFileIn       string
+		idleThresholdIn   int64
+		pushTimeoutIn     int64
 		expectedStatus    int
 		expectedError     bool
 		expectedIncludes  []string
@@ -100,6 +125,323 @@ func TestCheckArgs(t *testing.T) {
 			expectedError:     true,
 			expectedIncludes:  []string{},
 			expectedExcludes:  []string{},
+		}, {
+			name:             "max interfaces positive",
+			includesIn:       []string{},
+			excludesIn:       []string{},
+			maxInterfacesIn:  10,
+			expectedStatus:   sensu.CheckStateOK,
+			expectedError:    false,
+			expectedIncludes: []string{},
+			expectedExcludes: []string{},
+		}, {
+			name:             "max interfaces negative",
+			includesIn:       []string{},
+			excludesIn:       []string{},
+			maxInterfacesIn:  -1,
+			expectedStatus:   sensu.CheckStateCritical,
+			expectedError:    true,
+			expectedIncludes: []string{},
+			expectedExcludes: []string{},
+		}, {
+			name:             "node-exporter naming",
+			includesIn:       []string{},
+			excludesIn:       []string{},
+			namingIn:         namingNodeExporter,
+			expectedStatus:   sensu.CheckStateOK,
+			expectedError:    false,
+			expectedIncludes: []string{},
+			expectedExcludes: []string{},
+		}, {
+			name:             "invalid naming",
+			includesIn:       []string{},
+			excludesIn:       []string{},
+			namingIn:         "graphite",
+			expectedStatus:   sensu.CheckStateCritical,
+			expectedError:    true,
+			expectedIncludes: []string{},
+			expectedExcludes: []string{},
+		}, {
+			name:             "metric prefix and labels",
+			includesIn:       []string{},
+			excludesIn:       []string{},
+			metricPrefixIn:   "sensu_net_",
+			labelsIn:         map[string]string{"region": "us-west-2"},
+			expectedStatus:   sensu.CheckStateOK,
+			expectedError:    false,
+			expectedIncludes: []string{},
+			expectedExcludes: []string{},
+		}, {
+			name:             "invalid metric prefix",
+			includesIn:       []string{},
+			excludesIn:       []string{},
+			metricPrefixIn:   "sensu-net",
+			expectedStatus:   sensu.CheckStateCritical,
+			expectedError:    true,
+			expectedIncludes: []string{},
+			expectedExcludes: []string{},
+		}, {
+			name:             "reserved label",
+			includesIn:       []string{},
+			excludesIn:       []string{},
+			labelsIn:         map[string]string{"interface": "eno1"},
+			expectedStatus:   sensu.CheckStateCritical,
+			expectedError:    true,
+			expectedIncludes: []string{},
+			expectedExcludes: []string{},
+		}, {
+			name:             "include metrics",
+			includesIn:       []string{},
+			excludesIn:       []string{},
+			includeMetricsIn: []string{" bytes_*_rate", "/^err_.*$/ "},
+			expectedStatus:   sensu.CheckStateOK,
+			expectedError:    false,
+			expectedIncludes: []string{},
+			expectedExcludes: []string{},
+		}, {
+			name:             "invalid include metrics",
+			includesIn:       []string{},
+			excludesIn:       []string{},
+			includeMetricsIn: []string{"/bytes_(recv/"},
+			expectedStatus:   sensu.CheckStateCritical,
+			expectedError:    true,
+			expectedIncludes: []string{},
+			expectedExcludes: []string{},
+		}, {
+			name:             "rate unit",
+			includesIn:       []string{},
+			excludesIn:       []string{},
+			rateUnitIn:       rateUnitMbit,
+			expectedStatus:   sensu.CheckStateOK,
+			expectedError:    false,
+			expectedIncludes: []string{},
+			expectedExcludes: []string{},
+		}, {
+			name:             "invalid rate unit",
+			includesIn:       []string{},
+			excludesIn:       []string{},
+			rateUnitIn:       "Gbit",
+			expectedStatus:   sensu.CheckStateCritical,
+			expectedError:    true,
+			expectedIncludes: []string{},
+			expectedExcludes: []string{},
+		}, {
+			name:             "nagios output format and thresholds",
+			includesIn:       []string{},
+			excludesIn:       []string{},
+			outputFormatIn:   outputFormatNagios,
+			warningIn:        map[string]string{"err_in_rate": "10"},
+			expectedStatus:   sensu.CheckStateOK,
+			expectedError:    false,
+			expectedIncludes: []string{},
+			expectedExcludes: []string{},
+		}, {
+			name:             "invalid output format",
+			includesIn:       []string{},
+			excludesIn:       []string{},
+			outputFormatIn:   "xml",
+			expectedStatus:   sensu.CheckStateCritical,
+			expectedError:    true,
+			expectedIncludes: []string{},
+			expectedExcludes: []string{},
+		}, {
+			name:             "invalid threshold",
+			includesIn:       []string{},
+			excludesIn:       []string{},
+			warningIn:        map[string]string{"err_in_rate": "ten"},
+			expectedStatus:   sensu.CheckStateCritical,
+			expectedError:    true,
+			expectedIncludes: []string{},
+			expectedExcludes: []string{},
+		}, {
+			name:             "graphite output format",
+			includesIn:       []string{},
+			excludesIn:       []string{},
+			outputFormatIn:   outputFormatGraphite,
+			graphiteTemplate: "{host}.{metric}.{interface}",
+			expectedStatus:   sensu.CheckStateOK,
+			expectedError:    false,
+			expectedIncludes: []string{},
+			expectedExcludes: []string{},
+		}, {
+			name:             "invalid graphite template",
+			includesIn:       []string{},
+			excludesIn:       []string{},
+			outputFormatIn:   outputFormatGraphite,
+			graphiteTemplate: "{host}.{interface}",
+			expectedStatus:   sensu.CheckStateCritical,
+			expectedError:    true,
+			expectedIncludes: []string{},
+			expectedExcludes: []string{},
+		}, {
+			name:             "textfile directory",
+			includesIn:       []string{},
+			excludesIn:       []string{},
+			textfileDirIn:    os.TempDir(),
+			expectedStatus:   sensu.CheckStateOK,
+			expectedError:    false,
+			expectedIncludes: []string{},
+			expectedExcludes: []string{},
+		}, {
+			name:             "missing textfile directory",
+			includesIn:       []string{},
+			excludesIn:       []string{},
+			textfileDirIn:    filepath.Join(os.TempDir(), "network-interface-checks-missing"),
+			expectedStatus:   sensu.CheckStateCritical,
+			expectedError:    true,
+			expectedIncludes: []string{},
+			expectedExcludes: []string{},
+		}, {
+			name:             "pushgateway with basic authentication",
+			includesIn:       []string{},
+			excludesIn:       []string{},
+			pushgatewayURLIn: "http://pushgateway:9091",
+			pushUsernameIn:   "user",
+			expectedStatus:   sensu.CheckStateOK,
+			expectedError:    false,
+			expectedIncludes: []string{},
+			expectedExcludes: []string{},
+		}, {
+			name:             "invalid pushgateway url",
+			includesIn:       []string{},
+			excludesIn:       []string{},
+			pushgatewayURLIn: "pushgateway:9091",
+			expectedStatus:   sensu.CheckStateCritical,
+			expectedError:    true,
+			expectedIncludes: []string{},
+			expectedExcludes: []string{},
+		}, {
+			name:             "basic authentication and bearer token",
+			includesIn:       []string{},
+			excludesIn:       []string{},
+			pushgatewayURLIn: "http://pushgateway:9091",
+			pushUsernameIn:   "user",
+			pushBearerIn:     "token",
+			expectedStatus:   sensu.CheckStateCritical,
+			expectedError:    true,
+			expectedIncludes: []string{},
+			expectedExcludes: []string{},
+		}, {
+			name:              "traffic timezone and quotas",
+			includesIn:        []string{},
+			excludesIn:        []string{},
+			trafficTimezoneIn: "Europe/Paris",
+			trafficQuotasIn:   []string{"eth0=10TB/month", "all=500GiB/day"},
+			expectedStatus:    sensu.CheckStateOK,
+			expectedError:     false,
+			expectedIncludes:  []string{},
+			expectedExcludes:  []string{},
+		}, {
+			name:              "invalid traffic timezone",
+			includesIn:        []string{},
+			excludesIn:        []string{},
+			trafficTimezoneIn: "Mars/Olympus_Mons",
+			expectedStatus:    sensu.CheckStateCritical,
+			expectedError:     true,
+			expectedIncludes:  []string{},
+			expectedExcludes:  []string{},
+		}, {
+			name:             "invalid traffic quota",
+			includesIn:       []string{},
+			excludesIn:       []string{},
+			trafficQuotasIn:  []string{"eth0=10TB/week"},
+			expectedStatus:   sensu.CheckStateCritical,
+			expectedError:    true,
+			expectedIncludes: []string{},
+			expectedExcludes: []string{},
+		}, {
+			name:             "invalid anomaly alpha",
+			includesIn:       []string{},
+			excludesIn:       []string{},
+			anomalyAlphaIn:   1.5,
+			expectedStatus:   sensu.CheckStateCritical,
+			expectedError:    true,
+			expectedIncludes: []string{},
+			expectedExcludes: []string{},
+		}, {
+			name:             "negative alert raise after",
+			includesIn:       []string{},
+			excludesIn:       []string{},
+			raiseAfterIn:     -1,
+			expectedStatus:   sensu.CheckStateCritical,
+			expectedError:    true,
+			expectedIncludes: []string{},
+			expectedExcludes: []string{},
+		}, {
+			name:             "alert raise after without state file",
+			includesIn:       []string{},
+			excludesIn:       []string{},
+			raiseAfterIn:     3,
+			expectedStatus:   sensu.CheckStateCritical,
+			expectedError:    true,
+			expectedIncludes: []string{},
+			expectedExcludes: []string{},
+		}, {
+			name:             "alert clear after without state file",
+			includesIn:       []string{},
+			excludesIn:       []string{},
+			clearAfterIn:     2,
+			expectedStatus:   sensu.CheckStateCritical,
+			expectedError:    true,
+			expectedIncludes: []string{},
+			expectedExcludes: []string{},
+		}, {
+			name:             "alert raise and clear after with state file",
+			includesIn:       []string{},
+			excludesIn:       []string{},
+			raiseAfterIn:     3,
+			clearAfterIn:     2,
+			stateFileIn:      "/tmp/network-interface-checks.json",
+			expectedStatus:   sensu.CheckStateOK,
+			expectedError:    false,
+			expectedIncludes: []string{},
+			expectedExcludes: []string{},
+		}, {
+			name:             "negative idle threshold",
+			includesIn:       []string{},
+			excludesIn:       []string{},
+			idleThresholdIn:  -60,
+			expectedStatus:   sensu.CheckStateCritical,
+			expectedError:    true,
+			expectedIncludes: []string{},
+			expectedExcludes: []string{},
+		}, {
+			name:             "quota warning above critical",
+			includesIn:       []string{},
+			excludesIn:       []string{},
+			quotaWarningIn:   90,
+			quotaCriticalIn:  50,
+			expectedStatus:   sensu.CheckStateCritical,
+			expectedError:    true,
+			expectedIncludes: []string{},
+			expectedExcludes: []string{},
+		}, {
+			name:             "quota warning above the default critical",
+			includesIn:       []string{},
+			excludesIn:       []string{},
+			quotaWarningIn:   120,
+			expectedStatus:   sensu.CheckStateCritical,
+			expectedError:    true,
+			expectedIncludes: []string{},
+			expectedExcludes: []string{},
+		}, {
+			name:             "delta threshold without delta metrics",
+			includesIn:       []string{},
+			excludesIn:       []string{},
+			warningIn:        map[string]string{"err_in_delta": "5"},
+			expectedStatus:   sensu.CheckStateCritical,
+			expectedError:    true,
+			expectedIncludes: []string{},
+			expectedExcludes: []string{},
+		}, {
+			name:             "negative push timeout",
+			includesIn:       []string{},
+			excludesIn:       []string{},
+			pushTimeoutIn:    -1,
+			expectedStatus:   sensu.CheckStateCritical,
+			expectedError:    true,
+			expectedIncludes: []string{},
+			expectedExcludes: []string{},
 		},
 	}
 
@@ -111,6 +453,29 @@ func TestCheckArgs(t *testing.T) {
 				IncludeInterfaces:      testCase.includesIn,
 				ExcludeInterfaces:      testCase.excludesIn,
 				MaxRateIntervalSeconds: testCase.maxRateIntervalIn,
+				MaxInterfaces:          testCase.maxInterfacesIn,
+				Naming:                 testCase.namingIn,
+				MetricPrefix:           testCase.metricPrefixIn,
+				Labels:                 testCase.labelsIn,
+				IncludeMetrics:         testCase.includeMetricsIn,
+				RateUnit:               testCase.rateUnitIn,
+				OutputFormat:           testCase.outputFormatIn,
+				Warning:                testCase.warningIn,
+				GraphiteTemplate:       testCase.graphiteTemplate,
+				TextfileDir:            testCase.textfileDirIn,
+				PushgatewayURL:         testCase.pushgatewayURLIn,
+				PushUsername:           testCase.pushUsernameIn,
+				PushBearerToken:        testCase.pushBearerIn,
+				TrafficTimezone:        testCase.trafficTimezoneIn,
+				TrafficQuotas:          testCase.trafficQuotasIn,
+				TrafficQuotaWarning:    testCase.quotaWarningIn,
+				TrafficQuotaCritical:   testCase.quotaCriticalIn,
+				AnomalyAlpha:           testCase.anomalyAlphaIn,
+				AlertRaiseAfter:        testCase.raiseAfterIn,
+				AlertClearAfter:        testCase.clearAfterIn,
+				StateFile:              testCase.stateFileIn,
+				IdleThreshold:          testCase.idleThresholdIn,
+				PushTimeout:            testCase.pushTimeoutIn,
 			}
 
 			status, err := checkArgs(nil)
@@ -125,3 +490,11 @@ func TestCheckArgs(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateArgs_AlertInMemory(t *testing.T) {
+	// the serve subcommand keeps the alert state in memory
+	plugin = Config{AlertRaiseAfter: 3, AlertClearAfter: 2}
+	status, err := validateArgs(nil)
+	assert.Equal(t, sensu.CheckStateOK, status)
+	assert.NoError(t, err)
+}
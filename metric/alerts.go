@@ -0,0 +1,56 @@
+package metric
+
+// AlertState is the state of an alert rule on an interface across runs, with the status and message of its last
+// breach.
+type AlertState struct {
+	Status    int    `json:"status"`
+	Rule      string `json:"rule"`
+	Interface string `json:"interface"`
+	Message   string `json:"message"`
+	Breaches  int    `json:"breaches,omitempty"`
+	Clears    int    `json:"clears,omitempty"`
+	Raised    bool   `json:"raised,omitempty"`
+}
+
+// UpdateAlerts records the alerts breached by the current run and returns the raised alerts. An alert is raised once
+// breached by raiseAfter consecutive runs, and cleared once not breached by clearAfter consecutive runs. Meanwhile a
+// raised alert keeps the status and message of its last breach.
+func (s *CounterMetricState) UpdateAlerts(breaches []AlertState, raiseAfter, clearAfter int) []AlertState {
+	breached := map[string]struct{}{}
+	for _, breach := range breaches {
+		key := getAlertKey(breach)
+		breached[key] = struct{}{}
+		alert := s.alerts[key]
+		if alert == nil {
+			alert = &AlertState{}
+			s.alerts[key] = alert
+		}
+		alert.Status, alert.Rule, alert.Interface, alert.Message = breach.Status, breach.Rule, breach.Interface, breach.Message
+		alert.Breaches++
+		alert.Clears = 0
+		if alert.Breaches >= raiseAfter {
+			alert.Raised = true
+		}
+	}
+
+	raised := make([]AlertState, 0)
+	for key, alert := range s.alerts {
+		if _, ok := breached[key]; !ok {
+			alert.Breaches = 0
+			alert.Clears++
+			if !alert.Raised || alert.Clears >= clearAfter {
+				delete(s.alerts, key)
+				continue
+			}
+		}
+		if alert.Raised {
+			raised = append(raised, *alert)
+		}
+	}
+
+	return raised
+}
+
+func getAlertKey(alert AlertState) string {
+	return alert.Rule + "-interface=" + alert.Interface
+}
@@ -0,0 +1,36 @@
+package metric
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCounterMetricState_UpdateAlerts(t *testing.T) {
+	warning := AlertState{Status: 1, Rule: "threshold:err_in", Interface: "eth0", Message: "err_in on eth0 is 5 (>= 1)"}
+	critical := AlertState{Status: 2, Rule: "threshold:err_in", Interface: "eth0", Message: "err_in on eth0 is 50 (>= 10)"}
+	other := AlertState{Status: 1, Rule: "threshold:err_in", Interface: "eth1", Message: "err_in on eth1 is 5 (>= 1)"}
+	raisedState := func(alert AlertState, breaches, clears int) AlertState {
+		alert.Breaches, alert.Clears, alert.Raised = breaches, clears, true
+		return alert
+	}
+	metricState := New()
+
+	// raised after 2 consecutive breaches
+	assert.Empty(t, metricState.UpdateAlerts([]AlertState{warning, other}, 2, 3))
+	assert.Equal(t, []AlertState{raisedState(warning, 2, 0)}, metricState.UpdateAlerts([]AlertState{warning}, 2, 3))
+
+	// the breach counter of eth1 was reset by the clean run
+	assert.Equal(t, []AlertState{raisedState(critical, 3, 0)}, metricState.UpdateAlerts([]AlertState{critical, other}, 2, 3))
+
+	// kept in the state, and cleared after 3 consecutive clean runs
+	buf := new(bytes.Buffer)
+	assert.NoError(t, metricState.Write(buf))
+	metricState = New()
+	assert.NoError(t, metricState.Read(buf))
+	assert.Equal(t, []AlertState{raisedState(critical, 0, 1)}, metricState.UpdateAlerts([]AlertState{}, 2, 3))
+	assert.Equal(t, []AlertState{raisedState(critical, 0, 2)}, metricState.UpdateAlerts([]AlertState{}, 2, 3))
+	assert.Empty(t, metricState.UpdateAlerts([]AlertState{}, 2, 3))
+	assert.Empty(t, metricState.alerts)
+}
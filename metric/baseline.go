@@ -0,0 +1,34 @@
+package metric
+
+import (
+	dto "github.com/prometheus/client_model/go"
+)
+
+// Baseline is the exponentially weighted moving average and variance of the rate of a counter, over Samples rates.
+type Baseline struct {
+	Mean     float64 `json:"mean"`
+	Variance float64 `json:"variance"`
+	Samples  int64   `json:"samples"`
+}
+
+// UpdateBaseline adds the rate to the baseline of the counter metric, with alpha the weight of the new rate, and
+// returns the baseline the rate was added to. The counter metric must have been recorded with AddMetric.
+func (s *CounterMetricState) UpdateBaseline(family *dto.MetricFamily, metric *dto.Metric, rate, alpha float64) Baseline {
+	counterMetric := s.metrics[getMetricKey(family, metric)]
+	if counterMetric == nil {
+		return Baseline{}
+	}
+	if counterMetric.Baseline == nil {
+		counterMetric.Baseline = &Baseline{Mean: rate, Samples: 1}
+		return Baseline{}
+	}
+
+	baseline := counterMetric.Baseline
+	previous := *baseline
+	diff := rate - baseline.Mean
+	increment := alpha * diff
+	baseline.Mean += increment
+	baseline.Variance = (1 - alpha) * (baseline.Variance + diff*increment)
+	baseline.Samples++
+	return previous
+}
@@ -0,0 +1,37 @@
+package metric
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCounterMetricState_UpdateBaseline(t *testing.T) {
+	family := newFamily1()
+
+	// unknown metric
+	metricState := New()
+	assert.Equal(t, Baseline{}, metricState.UpdateBaseline(family, newMetric1(10, 1000), 10, 0.5))
+
+	// the first rate starts the baseline
+	metricState.AddMetric(family, newMetric1(10, 1000))
+	assert.Equal(t, Baseline{}, metricState.UpdateBaseline(family, newMetric1(10, 1000), 10, 0.5))
+	metricState.AddMetric(family, newMetric1(30, 2000))
+	assert.Equal(t, Baseline{Mean: 10, Samples: 1}, metricState.UpdateBaseline(family, newMetric1(30, 2000), 20, 0.5))
+
+	// kept across counter resets
+	metricState.AddMetric(family, newMetric1(5, 3000))
+	assert.Equal(t, Baseline{Mean: 15, Variance: 25, Samples: 2},
+		metricState.UpdateBaseline(family, newMetric1(5, 3000), 15, 0.5))
+
+	// kept in the state
+	buf := new(bytes.Buffer)
+	assert.NoError(t, metricState.Write(buf))
+	assert.Contains(t, buf.String(), `"baseline":{"mean":15,"variance":12.5,"samples":3}`)
+	metricState = New()
+	assert.NoError(t, metricState.Read(buf))
+	metricState.AddMetric(family, newMetric1(10, 4000))
+	assert.Equal(t, Baseline{Mean: 15, Variance: 12.5, Samples: 3},
+		metricState.UpdateBaseline(family, newMetric1(10, 4000), 15, 0.5))
+}
@@ -11,18 +11,52 @@ import (
 	"strings"
 )
 
+// stateVersion is the version of the state file format. The legacy format, without version, is the map of the counter
+// metrics only.
+const stateVersion = 2
+
 type CounterMetric struct {
 	Value       float64 `json:"value"`
 	TimestampMS int64   `json:"timestamp"`
+	CreatedMS   int64   `json:"created,omitempty"`
+	// ChangedMS is the timestamp of the first value recorded since the value of the counter last changed, 0 when the
+	// value changed at TimestampMS.
+	ChangedMS int64 `json:"changed,omitempty"`
+	// Offset is the sum of the values the counter had before each of its resets, the lifetime value of the counter
+	// being Offset + Value.
+	Offset float64 `json:"offset,omitempty"`
+	// Baseline is the baseline of the rate of the counter, kept across its resets.
+	Baseline *Baseline `json:"baseline,omitempty"`
 }
 
 type CounterMetricState struct {
-	metrics map[string]*CounterMetric
+	metrics  map[string]*CounterMetric
+	traffic  map[string]*InterfaceTraffic
+	windows  map[string]*SampleWindow
+	alerts   map[string]*AlertState
+	bootID   string
+	rebooted bool
+	// seen holds the keys of the metrics, windows and traffic recorded since the state was last pruned.
+	seen map[string]struct{}
+}
+
+// stateFile is the JSON document of the state file.
+type stateFile struct {
+	Version int                          `json:"version"`
+	BootID  string                       `json:"boot_id,omitempty"`
+	Metrics map[string]*CounterMetric    `json:"metrics"`
+	Traffic map[string]*InterfaceTraffic `json:"traffic,omitempty"`
+	Windows map[string]*SampleWindow     `json:"windows,omitempty"`
+	Alerts  map[string]*AlertState       `json:"alerts,omitempty"`
 }
 
 func New() *CounterMetricState {
 	return &CounterMetricState{
 		metrics: make(map[string]*CounterMetric),
+		traffic: make(map[string]*InterfaceTraffic),
+		windows: make(map[string]*SampleWindow),
+		alerts:  make(map[string]*AlertState),
+		seen:    make(map[string]struct{}),
 	}
 }
 
@@ -53,12 +87,73 @@ func NewFromFile(filename string) (*CounterMetricState, error) {
 	return counterMetricState, nil
 }
 
+// SetBootID records the boot ID of the host. The host rebooted since the state was written when the boot ID differs
+// from the one of the state, in which case every counter is considered reset. An empty boot ID is ignored.
+func (s *CounterMetricState) SetBootID(bootID string) {
+	if bootID == "" {
+		return
+	}
+	s.rebooted = s.bootID != "" && s.bootID != bootID
+	s.bootID = bootID
+}
+
+// Rebooted returns whether the host rebooted since the state was written.
+func (s *CounterMetricState) Rebooted() bool {
+	return s.rebooted
+}
+
+// AddMetric records the value of the counter metric. The creation timestamp and the offset of the counter are kept
+// from the previous value unless the counter decreased or the host rebooted, which means it was reset. The previous
+// value is then added to the offset.
 func (s *CounterMetricState) AddMetric(family *dto.MetricFamily, metric *dto.Metric) {
 	key := getMetricKey(family, metric)
-	s.metrics[key] = &CounterMetric{
+	counterMetric := &CounterMetric{
 		Value:       metric.GetCounter().GetValue(),
 		TimestampMS: metric.GetTimestampMs(),
+		CreatedMS:   metric.GetTimestampMs(),
 	}
+	if prev := s.metrics[key]; prev != nil {
+		counterMetric.Baseline = prev.Baseline
+		if counterMetric.Value == prev.Value && !s.rebooted {
+			counterMetric.ChangedMS = prev.ChangedMS
+			if counterMetric.ChangedMS == 0 {
+				counterMetric.ChangedMS = prev.TimestampMS
+			}
+		}
+		if counterMetric.Value >= prev.Value && !s.rebooted {
+			counterMetric.Offset = prev.Offset
+			counterMetric.CreatedMS = prev.CreatedMS
+			if counterMetric.CreatedMS == 0 {
+				counterMetric.CreatedMS = prev.TimestampMS
+			}
+		} else {
+			counterMetric.Offset = prev.Offset + prev.Value
+		}
+	}
+	s.metrics[key] = counterMetric
+	s.seen["metrics/"+key] = struct{}{}
+}
+
+// Prune removes the metrics, with their baseline, the windows and the traffic that were not recorded since the state
+// was last pruned, e.g. those of interfaces removed from the host, so that a long-lived state doesn't grow with every
+// interface ever seen. The alerts are not pruned, UpdateAlerts removes those no longer breached.
+func (s *CounterMetricState) Prune() {
+	for key := range s.metrics {
+		if _, ok := s.seen["metrics/"+key]; !ok {
+			delete(s.metrics, key)
+		}
+	}
+	for key := range s.windows {
+		if _, ok := s.seen["windows/"+key]; !ok {
+			delete(s.windows, key)
+		}
+	}
+	for ifName := range s.traffic {
+		if _, ok := s.seen["traffic/"+ifName]; !ok {
+			delete(s.traffic, ifName)
+		}
+	}
+	s.seen = make(map[string]struct{})
 }
 
 func (s *CounterMetricState) GetMetric(family *dto.MetricFamily, metric *dto.Metric) (bool, float64, int64) {
@@ -70,8 +165,49 @@ func (s *CounterMetricState) GetMetric(family *dto.MetricFamily, metric *dto.Met
 	return true, metricState.Value, metricState.TimestampMS
 }
 
+// GetCreated returns the timestamp of the first value recorded for the counter metric since it was last reset.
+func (s *CounterMetricState) GetCreated(family *dto.MetricFamily, metric *dto.Metric) (bool, int64) {
+	key := getMetricKey(family, metric)
+	metricState := s.metrics[key]
+	if metricState == nil || metricState.CreatedMS == 0 {
+		return false, 0
+	}
+	return true, metricState.CreatedMS
+}
+
+// GetChanged returns the timestamp of the first value recorded since the value of the counter metric last changed.
+func (s *CounterMetricState) GetChanged(family *dto.MetricFamily, metric *dto.Metric) (bool, int64) {
+	key := getMetricKey(family, metric)
+	metricState := s.metrics[key]
+	if metricState == nil {
+		return false, 0
+	}
+	if metricState.ChangedMS == 0 {
+		return true, metricState.TimestampMS
+	}
+	return true, metricState.ChangedMS
+}
+
+// GetLifetime returns the lifetime value of the counter metric, which is its value plus the values it had before each
+// of its resets.
+func (s *CounterMetricState) GetLifetime(family *dto.MetricFamily, metric *dto.Metric) (bool, float64) {
+	key := getMetricKey(family, metric)
+	metricState := s.metrics[key]
+	if metricState == nil {
+		return false, 0
+	}
+	return true, metricState.Offset + metricState.Value
+}
+
 func (s *CounterMetricState) Write(writer io.Writer) error {
-	content, err := json.Marshal(s.metrics)
+	content, err := json.Marshal(&stateFile{
+		Version: stateVersion,
+		BootID:  s.bootID,
+		Metrics: s.metrics,
+		Traffic: s.traffic,
+		Windows: s.windows,
+		Alerts:  s.alerts,
+	})
 	if err != nil {
 		return fmt.Errorf("error creating json document: %v", err)
 	}
@@ -89,10 +225,36 @@ func (s *CounterMetricState) Read(reader io.Reader) error {
 	if err != nil {
 		return fmt.Errorf("error reading metric state content: %v", err)
 	}
-	err = json.Unmarshal(content, &s.metrics)
+	var state stateFile
+	err = json.Unmarshal(content, &state)
+	if err == nil && state.Version == 0 {
+		// legacy state, without version
+		state.Metrics = nil
+		err = json.Unmarshal(content, &state.Metrics)
+	}
 	if err != nil {
 		return fmt.Errorf("error unmarshalling json metric state content: %v", err)
 	}
+	if state.Version > stateVersion {
+		return fmt.Errorf("unsupported metric state version %d", state.Version)
+	}
+	if state.Metrics == nil {
+		state.Metrics = make(map[string]*CounterMetric)
+	}
+	if state.Traffic == nil {
+		state.Traffic = make(map[string]*InterfaceTraffic)
+	}
+	if state.Windows == nil {
+		state.Windows = make(map[string]*SampleWindow)
+	}
+	if state.Alerts == nil {
+		state.Alerts = make(map[string]*AlertState)
+	}
+	s.metrics = state.Metrics
+	s.traffic = state.Traffic
+	s.windows = state.Windows
+	s.alerts = state.Alerts
+	s.bootID = state.BootID
 	return nil
 }
 
@@ -7,11 +7,14 @@ import (
 	"github.com/stretchr/testify/assert"
 	"strings"
 	"testing"
+	"time"
 )
 
 const (
 	bufferError = "buffer-error"
-	jsonState   = `{"my_metric-label1=label1Value":{"value":1234.5678,"timestamp":1639776815123},"my_other_metric-label21=label21Value":{"value":456.789,"timestamp":1639666815456},"my_other_metric-label221=label22Value-label222=label222Value":{"value":987.21,"timestamp":1639666815789}}`
+	jsonState   = `{"version":2,"metrics":{"my_metric-label1=label1Value":{"value":1234.5678,"timestamp":1639776815123,"created":1639666815123},"my_other_metric-label21=label21Value":{"value":456.789,"timestamp":1639666815456,"created":1639666815456},"my_other_metric-label221=label22Value-label222=label222Value":{"value":987.21,"timestamp":1639666815789,"created":1639666815789}}}`
+	flatState   = `{"my_metric-label1=label1Value":{"value":1234.5678,"timestamp":1639776815123,"created":1639666815123},"my_other_metric-label21=label21Value":{"value":456.789,"timestamp":1639666815456,"created":1639666815456},"my_other_metric-label221=label22Value-label222=label222Value":{"value":987.21,"timestamp":1639666815789,"created":1639666815789}}`
+	legacyState = `{"my_metric-label1=label1Value":{"value":1234.5678,"timestamp":1639776815123}}`
 )
 
 type ErrorReadWriter struct {
@@ -51,6 +54,35 @@ var (
 	metric22LabelValue2 = "label222Value"
 )
 
+// newFamily1 returns an empty family1 counter family.
+func newFamily1() *dto.MetricFamily {
+	return &dto.MetricFamily{
+		Name:   &family1Name,
+		Help:   &family1Help,
+		Type:   &metricType,
+		Metric: []*dto.Metric{},
+	}
+}
+
+// newMetric1 returns a family1 counter having the metric1 label.
+func newMetric1(value float64, timestampMS int64) *dto.Metric {
+	return newLabeledMetric(metric1labelName, metric1labelValue, value, timestampMS)
+}
+
+// newLabeledMetric returns a counter having a single label.
+func newLabeledMetric(labelName, labelValue string, value float64, timestampMS int64) *dto.Metric {
+	return &dto.Metric{
+		Label: []*dto.LabelPair{{
+			Name:  &labelName,
+			Value: &labelValue,
+		}},
+		Counter: &dto.Counter{
+			Value: &value,
+		},
+		TimestampMs: &timestampMS,
+	}
+}
+
 func TestCounterMetricState_AddGetMetric(t *testing.T) {
 	family1 := &dto.MetricFamily{
 		Name:   &family1Name,
@@ -136,25 +168,206 @@ func TestCounterMetricState_AddGetMetric(t *testing.T) {
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), bufferError)
 
-	// Read - make sure all 3 metrics are there
-	metricState = New()
-	err = metricState.Read(strings.NewReader(jsonState))
-	assert.NoError(t, err)
-	found, value, timestampMS = metricState.GetMetric(family1, metric1)
-	assert.True(t, found)
-	assert.Equal(t, metric1NewValue, value)
-	assert.Equal(t, metric1NewTimestampMS, timestampMS)
-	found, value, timestampMS = metricState.GetMetric(family2, metric21)
-	assert.True(t, found)
-	assert.Equal(t, metric21Value, value)
-	assert.Equal(t, metric21TimestampMS, timestampMS)
-	found, value, timestampMS = metricState.GetMetric(family2, metric22)
-	assert.True(t, found)
-	assert.Equal(t, metric22Value, value)
-	assert.Equal(t, metric22TimestampMS, timestampMS)
+	// Read - make sure all 3 metrics are there, from the current and the legacy formats
+	for _, content := range []string{jsonState, flatState} {
+		metricState = New()
+		err = metricState.Read(strings.NewReader(content))
+		assert.NoError(t, err)
+		found, value, timestampMS = metricState.GetMetric(family1, metric1)
+		assert.True(t, found)
+		assert.Equal(t, metric1NewValue, value)
+		assert.Equal(t, metric1NewTimestampMS, timestampMS)
+		found, value, timestampMS = metricState.GetMetric(family2, metric21)
+		assert.True(t, found)
+		assert.Equal(t, metric21Value, value)
+		assert.Equal(t, metric21TimestampMS, timestampMS)
+		found, value, timestampMS = metricState.GetMetric(family2, metric22)
+		assert.True(t, found)
+		assert.Equal(t, metric22Value, value)
+		assert.Equal(t, metric22TimestampMS, timestampMS)
+
+		// Created timestamps
+		found, createdMS := metricState.GetCreated(family1, metric1)
+		assert.True(t, found)
+		assert.Equal(t, metric1TimestampMS, createdMS)
+		found, createdMS = metricState.GetCreated(family2, metric22)
+		assert.True(t, found)
+		assert.Equal(t, metric22TimestampMS, createdMS)
+	}
 
 	// Read error
 	err = metricState.Read(&ErrorReadWriter{})
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), bufferError)
 }
+
+func TestCounterMetricState_Created(t *testing.T) {
+	family := newFamily1()
+
+	// unknown metric
+	metricState := New()
+	found, _ := metricState.GetCreated(family, newMetric1(1, 1000))
+	assert.False(t, found)
+
+	// created when first added and kept while the counter increases
+	metricState.AddMetric(family, newMetric1(10, 1000))
+	metricState.AddMetric(family, newMetric1(20, 2000))
+	found, createdMS := metricState.GetCreated(family, newMetric1(20, 2000))
+	assert.True(t, found)
+	assert.Equal(t, int64(1000), createdMS)
+
+	// counter reset
+	metricState.AddMetric(family, newMetric1(5, 3000))
+	_, createdMS = metricState.GetCreated(family, newMetric1(5, 3000))
+	assert.Equal(t, int64(3000), createdMS)
+
+	// state written before creation timestamps were recorded
+	metricState = New()
+	assert.NoError(t, metricState.Read(strings.NewReader(legacyState)))
+	found, _ = metricState.GetCreated(family, newMetric1(1234.5678, 1639776815123))
+	assert.False(t, found)
+	metricState.AddMetric(family, newMetric1(2000, 1639776875123))
+	_, createdMS = metricState.GetCreated(family, newMetric1(2000, 1639776875123))
+	assert.Equal(t, int64(1639776815123), createdMS)
+}
+
+func TestCounterMetricState_Changed(t *testing.T) {
+	family := newFamily1()
+
+	// unknown metric
+	metricState := New()
+	metricState.SetBootID("boot1")
+	found, _ := metricState.GetChanged(family, newMetric1(1, 1000))
+	assert.False(t, found)
+
+	// changed when first added and each time the value changes
+	metricState.AddMetric(family, newMetric1(10, 1000))
+	found, changedMS := metricState.GetChanged(family, newMetric1(10, 1000))
+	assert.True(t, found)
+	assert.Equal(t, int64(1000), changedMS)
+	metricState.AddMetric(family, newMetric1(20, 2000))
+	_, changedMS = metricState.GetChanged(family, newMetric1(20, 2000))
+	assert.Equal(t, int64(2000), changedMS)
+
+	// kept while the value doesn't change, and in the state
+	metricState.AddMetric(family, newMetric1(20, 3000))
+	metricState.AddMetric(family, newMetric1(20, 4000))
+	buf := new(bytes.Buffer)
+	assert.NoError(t, metricState.Write(buf))
+	assert.Contains(t, buf.String(), `"changed":2000`)
+	metricState = New()
+	assert.NoError(t, metricState.Read(bytes.NewReader(buf.Bytes())))
+	metricState.SetBootID("boot1")
+	metricState.AddMetric(family, newMetric1(20, 5000))
+	_, changedMS = metricState.GetChanged(family, newMetric1(20, 5000))
+	assert.Equal(t, int64(2000), changedMS)
+
+	// the counter restarted from the same value after a reboot
+	metricState = New()
+	assert.NoError(t, metricState.Read(bytes.NewReader(buf.Bytes())))
+	metricState.SetBootID("boot2")
+	metricState.AddMetric(family, newMetric1(20, 5000))
+	_, changedMS = metricState.GetChanged(family, newMetric1(20, 5000))
+	assert.Equal(t, int64(5000), changedMS)
+}
+
+func TestCounterMetricState_Lifetime(t *testing.T) {
+	family := newFamily1()
+
+	// unknown metric
+	metricState := New()
+	metricState.SetBootID("boot1")
+	found, _ := metricState.GetLifetime(family, newMetric1(1, 1000))
+	assert.False(t, found)
+
+	metricState.AddMetric(family, newMetric1(10, 1000))
+	metricState.AddMetric(family, newMetric1(20, 2000))
+	found, lifetime := metricState.GetLifetime(family, newMetric1(20, 2000))
+	assert.True(t, found)
+	assert.Equal(t, float64(20), lifetime)
+
+	// counter reset, the previous value is added to the offset
+	metricState.AddMetric(family, newMetric1(5, 3000))
+	_, lifetime = metricState.GetLifetime(family, newMetric1(5, 3000))
+	assert.Equal(t, float64(25), lifetime)
+
+	// the boot ID and the offset are kept in the state
+	buf := new(bytes.Buffer)
+	assert.NoError(t, metricState.Write(buf))
+	assert.Contains(t, buf.String(), `"boot_id":"boot1"`)
+	assert.Contains(t, buf.String(), `"offset":20`)
+
+	// same boot
+	metricState = New()
+	assert.NoError(t, metricState.Read(bytes.NewReader(buf.Bytes())))
+	metricState.SetBootID("boot1")
+	assert.False(t, metricState.Rebooted())
+	metricState.AddMetric(family, newMetric1(8, 4000))
+	_, lifetime = metricState.GetLifetime(family, newMetric1(8, 4000))
+	assert.Equal(t, float64(28), lifetime)
+
+	// reboot, the counter is reset even though it increased
+	metricState = New()
+	assert.NoError(t, metricState.Read(bytes.NewReader(buf.Bytes())))
+	metricState.SetBootID("boot2")
+	assert.True(t, metricState.Rebooted())
+	metricState.AddMetric(family, newMetric1(30, 5000))
+	_, lifetime = metricState.GetLifetime(family, newMetric1(30, 5000))
+	assert.Equal(t, float64(55), lifetime)
+	_, createdMS := metricState.GetCreated(family, newMetric1(30, 5000))
+	assert.Equal(t, int64(5000), createdMS)
+
+	// unknown boot ID
+	metricState = New()
+	assert.NoError(t, metricState.Read(bytes.NewReader(buf.Bytes())))
+	metricState.SetBootID("")
+	assert.False(t, metricState.Rebooted())
+}
+
+func TestCounterMetricState_Prune(t *testing.T) {
+	family := newFamily1()
+	newMetric := func(ifName string, value float64) *dto.Metric {
+		return newLabeledMetric("interface", ifName, value, 1000)
+	}
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	metricState := New()
+	for _, ifName := range []string{"eno1", "veth1"} {
+		metricState.AddMetric(family, newMetric(ifName, 10))
+		metricState.UpdateBaseline(family, newMetric(ifName, 10), 1, 0.5)
+		metricState.AddToWindow(family, newMetric(ifName, 10), 0, 0, 1000, 10)
+		metricState.AddTraffic(ifName, 10, now)
+	}
+	metricState.Prune()
+	assert.Len(t, metricState.metrics, 2)
+	assert.Len(t, metricState.windows, 2)
+	assert.Len(t, metricState.traffic, 2)
+
+	// veth1 was removed
+	metricState.AddMetric(family, newMetric("eno1", 20))
+	metricState.AddToWindow(family, newMetric("eno1", 20), 0, 1000, 2000, 10)
+	metricState.AddTraffic("eno1", 10, now)
+	metricState.Prune()
+	assert.Len(t, metricState.metrics, 1)
+	assert.NotNil(t, metricState.metrics["my_metric-interface=eno1"].Baseline)
+	assert.Len(t, metricState.windows, 1)
+	assert.Contains(t, metricState.windows, "my_metric-interface=eno1")
+	assert.Len(t, metricState.traffic, 1)
+	assert.Contains(t, metricState.traffic, "eno1")
+
+	// nothing recorded
+	metricState.Prune()
+	assert.Empty(t, metricState.metrics)
+	assert.Empty(t, metricState.windows)
+	assert.Empty(t, metricState.traffic)
+}
+
+func TestCounterMetricState_ReadVersion(t *testing.T) {
+	metricState := New()
+	assert.NoError(t, metricState.Read(strings.NewReader(`{"version":2,"boot_id":"boot1","metrics":null}`)))
+	metricState.AddMetric(&dto.MetricFamily{Name: &family1Name}, &dto.Metric{Counter: &dto.Counter{Value: &metric1Value}})
+
+	err := metricState.Read(strings.NewReader(`{"version":3,"metrics":{}}`))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "unsupported metric state version 3")
+}
@@ -0,0 +1,55 @@
+package metric
+
+import (
+	"time"
+)
+
+// TrafficBucket holds the bytes transferred during the period starting at StartMS.
+type TrafficBucket struct {
+	StartMS int64   `json:"start"`
+	Bytes   float64 `json:"bytes"`
+}
+
+// InterfaceTraffic holds the bytes received and sent by an interface during the current hour, day and month.
+type InterfaceTraffic struct {
+	Hour  TrafficBucket `json:"hour"`
+	Day   TrafficBucket `json:"day"`
+	Month TrafficBucket `json:"month"`
+}
+
+// AddTraffic adds the bytes to the traffic buckets of the interface and returns them. A bucket whose period ended
+// before now is started again from zero. Periods start at the beginning of the hour, day and month in the location
+// of now.
+func (s *CounterMetricState) AddTraffic(ifName string, bytes float64, now time.Time) *InterfaceTraffic {
+	traffic := s.traffic[ifName]
+	if traffic == nil {
+		traffic = &InterfaceTraffic{}
+		s.traffic[ifName] = traffic
+	}
+	s.seen["traffic/"+ifName] = struct{}{}
+
+	year, month, day := now.Date()
+	addToBucket(&traffic.Hour, time.Date(year, month, day, now.Hour(), 0, 0, 0, now.Location()), bytes)
+	addToBucket(&traffic.Day, time.Date(year, month, day, 0, 0, 0, 0, now.Location()), bytes)
+	addToBucket(&traffic.Month, time.Date(year, month, 1, 0, 0, 0, 0, now.Location()), bytes)
+
+	return traffic
+}
+
+// PruneTraffic removes the traffic of the interfaces whose month bucket ended before the month of now, e.g. those of
+// interfaces removed during a previous month.
+func (s *CounterMetricState) PruneTraffic(now time.Time) {
+	startMS := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location()).UnixMilli()
+	for ifName, traffic := range s.traffic {
+		if traffic.Month.StartMS < startMS {
+			delete(s.traffic, ifName)
+		}
+	}
+}
+
+func addToBucket(bucket *TrafficBucket, start time.Time, bytes float64) {
+	if bucket.StartMS != start.UnixMilli() {
+		*bucket = TrafficBucket{StartMS: start.UnixMilli()}
+	}
+	bucket.Bytes += bytes
+}
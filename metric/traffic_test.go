@@ -0,0 +1,68 @@
+package metric
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCounterMetricState_AddTraffic(t *testing.T) {
+	location, err := time.LoadLocation("America/New_York")
+	assert.NoError(t, err)
+	metricState := New()
+
+	traffic := metricState.AddTraffic("eth0", 100, time.Date(2022, 1, 31, 22, 10, 0, 0, location))
+	assert.Equal(t, float64(100), traffic.Hour.Bytes)
+	assert.Equal(t, time.Date(2022, 1, 31, 22, 0, 0, 0, location).UnixMilli(), traffic.Hour.StartMS)
+	assert.Equal(t, time.Date(2022, 1, 31, 0, 0, 0, 0, location).UnixMilli(), traffic.Day.StartMS)
+	assert.Equal(t, time.Date(2022, 1, 1, 0, 0, 0, 0, location).UnixMilli(), traffic.Month.StartMS)
+
+	// same hour
+	traffic = metricState.AddTraffic("eth0", 50, time.Date(2022, 1, 31, 22, 50, 0, 0, location))
+	assert.Equal(t, TrafficBucket{time.Date(2022, 1, 31, 22, 0, 0, 0, location).UnixMilli(), 150}, traffic.Hour)
+	assert.Equal(t, float64(150), traffic.Day.Bytes)
+	assert.Equal(t, float64(150), traffic.Month.Bytes)
+
+	// next hour
+	traffic = metricState.AddTraffic("eth0", 10, time.Date(2022, 1, 31, 23, 5, 0, 0, location))
+	assert.Equal(t, float64(10), traffic.Hour.Bytes)
+	assert.Equal(t, float64(160), traffic.Day.Bytes)
+	assert.Equal(t, float64(160), traffic.Month.Bytes)
+
+	// next day and month in the location, while still January 31st in UTC
+	traffic = metricState.AddTraffic("eth0", 5, time.Date(2022, 2, 1, 0, 5, 0, 0, location))
+	assert.Equal(t, float64(5), traffic.Hour.Bytes)
+	assert.Equal(t, float64(5), traffic.Day.Bytes)
+	assert.Equal(t, TrafficBucket{time.Date(2022, 2, 1, 0, 0, 0, 0, location).UnixMilli(), 5}, traffic.Month)
+
+	// interfaces have their own buckets
+	traffic = metricState.AddTraffic("eth1", 0, time.Date(2022, 2, 1, 0, 5, 0, 0, location))
+	assert.Equal(t, float64(0), traffic.Month.Bytes)
+
+	// kept in the state
+	buf := new(bytes.Buffer)
+	assert.NoError(t, metricState.Write(buf))
+	metricState = New()
+	assert.NoError(t, metricState.Read(buf))
+	traffic = metricState.AddTraffic("eth0", 1, time.Date(2022, 2, 15, 12, 0, 0, 0, location))
+	assert.Equal(t, float64(1), traffic.Day.Bytes)
+	assert.Equal(t, float64(6), traffic.Month.Bytes)
+}
+
+func TestCounterMetricState_PruneTraffic(t *testing.T) {
+	metricState := New()
+	metricState.AddTraffic("eth0", 100, time.Date(2022, 1, 31, 12, 0, 0, 0, time.UTC))
+	metricState.AddTraffic("eth1", 100, time.Date(2022, 1, 31, 12, 0, 0, 0, time.UTC))
+	metricState.AddTraffic("eth0", 10, time.Date(2022, 2, 1, 12, 0, 0, 0, time.UTC))
+
+	metricState.PruneTraffic(time.Date(2022, 2, 1, 13, 0, 0, 0, time.UTC))
+	assert.Len(t, metricState.traffic, 1)
+	assert.Equal(t, float64(10), metricState.traffic["eth0"].Month.Bytes)
+	assert.Equal(t, float64(10), metricState.traffic["eth0"].Hour.Bytes)
+
+	// kept during the month
+	metricState.PruneTraffic(time.Date(2022, 2, 28, 23, 0, 0, 0, time.UTC))
+	assert.Len(t, metricState.traffic, 1)
+}
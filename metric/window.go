@@ -0,0 +1,131 @@
+package metric
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+
+	dto "github.com/prometheus/client_model/go"
+)
+
+// SlotMS is the duration of a slot of the sample windows, 5 minutes being the sampling interval of percentile
+// billing.
+const SlotMS = 5 * 60 * 1000
+
+// SampleWindow holds the value accumulated in each slot of a period starting at StartMS, e.g. the bytes received
+// during each 5 minutes of a billing month. Slots without value are NaN.
+type SampleWindow struct {
+	StartMS int64      `json:"start"`
+	Slots   slotValues `json:"slots"`
+}
+
+// slotValues are stored as the base64 encoding of their little-endian float32 representation, which keeps a month
+// of 5 minute slots under 48KB.
+type slotValues []float32
+
+func (v slotValues) MarshalJSON() ([]byte, error) {
+	buf := make([]byte, 4*len(v))
+	for i, value := range v {
+		binary.LittleEndian.PutUint32(buf[4*i:], math.Float32bits(value))
+	}
+	return json.Marshal(base64.StdEncoding.EncodeToString(buf))
+}
+
+func (v *slotValues) UnmarshalJSON(data []byte) error {
+	var encoded string
+	if err := json.Unmarshal(data, &encoded); err != nil {
+		return err
+	}
+	buf, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return err
+	}
+	if len(buf)%4 != 0 {
+		return fmt.Errorf("invalid slot values length %d", len(buf))
+	}
+	*v = make(slotValues, len(buf)/4)
+	for i := range *v {
+		(*v)[i] = math.Float32frombits(binary.LittleEndian.Uint32(buf[4*i:]))
+	}
+	return nil
+}
+
+// AddToWindow spreads the value evenly over the slots between fromMS and toMS of the window of the metric, the part
+// before startMS being dropped. The window is started again when it started before startMS.
+func (s *CounterMetricState) AddToWindow(family *dto.MetricFamily, metric *dto.Metric, startMS, fromMS, toMS int64,
+	value float64) *SampleWindow {
+	key := getMetricKey(family, metric)
+	window := s.windows[key]
+	if window == nil || window.StartMS != startMS {
+		window = &SampleWindow{StartMS: startMS}
+		s.windows[key] = window
+	}
+	s.seen["windows/"+key] = struct{}{}
+	if toMS <= fromMS || toMS <= startMS {
+		return window
+	}
+
+	for slotStartMS := startMS + (maxInt64(fromMS, startMS)-startMS)/SlotMS*SlotMS; slotStartMS < toMS; slotStartMS += SlotMS {
+		overlapMS := minInt64(toMS, slotStartMS+SlotMS) - maxInt64(fromMS, slotStartMS)
+		slot := int((slotStartMS - startMS) / SlotMS)
+		for len(window.Slots) <= slot {
+			window.Slots = append(window.Slots, float32(math.NaN()))
+		}
+		if math.IsNaN(float64(window.Slots[slot])) {
+			window.Slots[slot] = 0
+		}
+		window.Slots[slot] += float32(value * float64(overlapMS) / float64(toMS-fromMS))
+	}
+
+	return window
+}
+
+// PruneWindows removes the windows started before startMS, e.g. those of interfaces removed during the last period.
+func (s *CounterMetricState) PruneWindows(startMS int64) {
+	for key, window := range s.windows {
+		if window.StartMS < startMS {
+			delete(s.windows, key)
+		}
+	}
+}
+
+// Percentile returns the nearest-rank percentile of the values of the slots ended at untilMS, skipping the slots
+// without value. It returns false when there is no such slot.
+func (w *SampleWindow) Percentile(percentile float64, untilMS int64) (float64, bool) {
+	values := make([]float64, 0, len(w.Slots))
+	for i, value := range w.Slots {
+		if w.StartMS+int64(i+1)*SlotMS > untilMS {
+			break
+		}
+		if !math.IsNaN(float64(value)) {
+			values = append(values, float64(value))
+		}
+	}
+	if len(values) == 0 {
+		return 0, false
+	}
+
+	sort.Float64s(values)
+	rank := int(math.Ceil(percentile / 100 * float64(len(values))))
+	if rank < 1 {
+		rank = 1
+	}
+	return values[rank-1], true
+}
+
+func minInt64(a, b int64) int64 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt64(a, b int64) int64 {
+	if a > b {
+		return a
+	}
+	return b
+}
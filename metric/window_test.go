@@ -0,0 +1,84 @@
+package metric
+
+import (
+	"bytes"
+	"math"
+	"testing"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCounterMetricState_AddToWindow(t *testing.T) {
+	family := &dto.MetricFamily{Name: &family1Name}
+	metric := &dto.Metric{Label: []*dto.LabelPair{{Name: &metric1labelName, Value: &metric1labelValue}}}
+	startMS := int64(1640995200000)
+	metricState := New()
+
+	// a single slot
+	window := metricState.AddToWindow(family, metric, startMS, startMS+60000, startMS+120000, 100)
+	assert.Equal(t, slotValues{100}, window.Slots)
+
+	// spread over two slots, the previous slots being kept
+	window = metricState.AddToWindow(family, metric, startMS, startMS+240000, startMS+360000, 200)
+	assert.Equal(t, slotValues{200, 100}, window.Slots)
+
+	// a gap leaves slots without value
+	window = metricState.AddToWindow(family, metric, startMS, startMS+3*SlotMS, startMS+3*SlotMS+1000, 10)
+	assert.Len(t, window.Slots, 4)
+	assert.True(t, math.IsNaN(float64(window.Slots[2])))
+	assert.Equal(t, float32(10), window.Slots[3])
+
+	// kept in the state
+	buf := new(bytes.Buffer)
+	assert.NoError(t, metricState.Write(buf))
+	metricState = New()
+	assert.NoError(t, metricState.Read(buf))
+	window = metricState.AddToWindow(family, metric, startMS, startMS+3*SlotMS+1000, startMS+3*SlotMS+2000, 5)
+	assert.Len(t, window.Slots, 4)
+	assert.True(t, math.IsNaN(float64(window.Slots[2])))
+	assert.Equal(t, float32(15), window.Slots[3])
+
+	// the part before the start of a new window is dropped
+	newStartMS := startMS + 10*SlotMS
+	window = metricState.AddToWindow(family, metric, newStartMS, newStartMS-SlotMS, newStartMS+SlotMS, 100)
+	assert.Equal(t, SampleWindow{StartMS: newStartMS, Slots: slotValues{50}}, *window)
+
+	// windows of previous periods are pruned
+	other := &dto.Metric{}
+	metricState.AddToWindow(family, other, startMS, startMS, startMS+1000, 1)
+	metricState.PruneWindows(newStartMS)
+	assert.Len(t, metricState.windows, 1)
+}
+
+func TestSampleWindow_Percentile(t *testing.T) {
+	window := &SampleWindow{StartMS: 0}
+	_, ok := window.Percentile(95, 10*SlotMS)
+	assert.False(t, ok)
+
+	for i := 1; i <= 20; i++ {
+		window.Slots = append(window.Slots, float32(i))
+	}
+	window.Slots = append(window.Slots, float32(math.NaN()), 1000)
+
+	// the last slot isn't complete
+	p95, ok := window.Percentile(95, 22*SlotMS-1)
+	assert.True(t, ok)
+	assert.Equal(t, float64(19), p95)
+
+	p95, _ = window.Percentile(95, 22*SlotMS)
+	assert.Equal(t, float64(20), p95)
+	p100, _ := window.Percentile(100, 22*SlotMS)
+	assert.Equal(t, float64(1000), p100)
+	p0, _ := window.Percentile(0, 22*SlotMS)
+	assert.Equal(t, float64(1), p0)
+}
+
+func TestSlotValues_UnmarshalJSON(t *testing.T) {
+	var values slotValues
+	assert.NoError(t, values.UnmarshalJSON([]byte(`"AADIQg=="`)))
+	assert.Equal(t, slotValues{100}, values)
+	assert.Error(t, values.UnmarshalJSON([]byte(`"AADI"`)))
+	assert.Error(t, values.UnmarshalJSON([]byte(`"!"`)))
+	assert.Error(t, values.UnmarshalJSON([]byte(`1`)))
+}
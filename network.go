@@ -3,6 +3,8 @@ package main
 import (
 	"fmt"
 	"os"
+	"sort"
+	"strings"
 	"time"
 
 	dto "github.com/prometheus/client_model/go"
@@ -10,6 +12,13 @@ import (
 	"github.com/sensu/network-interface-checks/metric"
 )
 
+const (
+	deltaFamilySuffix    = "_delta"
+	lifetimeFamilySuffix = "_lifetime"
+	deltaIntervalFamily  = "delta_interval_seconds"
+	deltaIntervalHelp    = "seconds between the previous sample of the interface and this one, over which the deltas are computed"
+)
+
 var (
 	metricHelp = map[string]string{
 		"bytes_sent":        "bytes sent",
@@ -28,8 +37,11 @@ var (
 		"drop_out_rate":     "outbound packets dropped per second",
 		"drop_in":           "incoming packets dropped",
 		"drop_in_rate":      "incoming packets dropped per second",
+		"bits_sent_rate":    "bits sent per second",
+		"bits_recv_rate":    "bits received per second",
 		"mtu":               "interface MTU configuration",
 		"host_net":          "SumoLogic Compatibility",
+		"interfaces_folded": "number of interfaces folded into interface=\"other\"",
 	}
 	interfaceLabel = "interface"
 	fieldLabel     = "field"
@@ -39,20 +51,155 @@ type MetricCollector struct {
 	selector               *selector
 	sum                    bool
 	sumologic              bool
+	sumoFieldNames         bool
 	stateFile              string
 	maxRateIntervalSeconds int64
+	maxInterfaces          int
+	naming                 string
+	metricPrefix           string
+	extraLabels            map[string]string
+	metricFilter           *metricFilter
+	rateUnit               string
+	thresholds             map[string]*threshold
+	outputThresholds       map[string]*threshold
+	alerts                 []Alert
+	createdMS              map[*dto.Metric]int64
+	deltas                 map[*dto.Metric]float64
+	deltaMetrics           bool
+	lifetime               bool
+	traffic                bool
+	trafficLocation        *time.Location
+	trafficQuotas          []trafficQuota
+	quotaWarning           float64
+	quotaCritical          float64
+	percentileInterfaces   map[string]struct{}
+	anomalyZScore          float64
+	anomalyAlpha           float64
+	anomalyWarmup          int64
+	anomalies              map[*dto.Metric]anomaly
+	alertRaiseAfter        int
+	alertClearAfter        int
+	idle                   bool
+	idleThreshold          int64
+	interfaceUp            func(ifName string) bool
+	// bootID identifies the current boot of the host, to detect the counter resets caused by a reboot.
+	bootID string
+	// memoryState keeps the counters across Collect calls instead of the state file, when the collector runs as an
+	// exporter.
+	memoryState *metric.CounterMetricState
 }
 
 // NetStats is the following: map[metric-name]map[interface-name]value
 type NetStats map[string]map[string]float64
 
-func NewCollector(includes, excludes []string, sum bool, sumologic bool, stateFile string, maxRateIntervalSeconds int64) (*MetricCollector, error) {
-	selector, err := NewDeviceSelector(includes, excludes)
+// CollectorOptions configures a MetricCollector.
+type CollectorOptions struct {
+	Includes  []string
+	Excludes  []string
+	Sum       bool
+	SumoLogic bool
+	// SumoLogicFieldNames names the host_net fields after the Sumo Logic Host Metrics source, and enables SumoLogic.
+	SumoLogicFieldNames    bool
+	StateFile              string
+	MaxRateIntervalSeconds int64
+	MaxInterfaces          int
+	Naming                 string
+	MetricPrefix           string
+	ExtraLabels            map[string]string
+	IncludeMetrics         []string
+	ExcludeMetrics         []string
+	RateUnit               string
+	Warning                map[string]string
+	Critical               map[string]string
+	DeltaMetrics           bool
+	LifetimeCounters       bool
+	TrafficAccounting      bool
+	// TrafficTimezone is the name of the location of the traffic rollover, the host time zone when empty.
+	TrafficTimezone      string
+	TrafficQuotas        []string
+	TrafficQuotaWarning  float64
+	TrafficQuotaCritical float64
+	// BillingPercentile lists the interfaces keeping a month of 5 minute samples for the 95th percentile rates,
+	// sumInterface standing for the sum.
+	BillingPercentile []string
+	AnomalyZScore     float64
+	AnomalyAlpha      float64
+	AnomalyWarmup     int64
+	AlertRaiseAfter   int
+	AlertClearAfter   int
+	IdleDetection     bool
+	IdleThreshold     int64
+	// InterfaceUp returns whether an interface is up, the idle detection skipping the interfaces that are not, all
+	// interfaces being up when nil.
+	InterfaceUp func(ifName string) bool
+	// BootID identifies the current boot of the host, empty when unknown.
+	BootID string
+}
+
+func NewCollector(options CollectorOptions) (*MetricCollector, error) {
+	selector, err := NewDeviceSelector(options.Includes, options.Excludes)
+	if err != nil {
+		return nil, err
+	}
+	filter, err := NewMetricFilter(options.IncludeMetrics, options.ExcludeMetrics)
+	if err != nil {
+		return nil, err
+	}
+	thresholds, err := parseThresholds(options.Warning, options.Critical)
+	if err != nil {
+		return nil, err
+	}
+	trafficLocation := time.Local
+	if options.TrafficTimezone != "" {
+		if trafficLocation, err = time.LoadLocation(options.TrafficTimezone); err != nil {
+			return nil, err
+		}
+	}
+	trafficQuotas, err := parseTrafficQuotas(options.TrafficQuotas)
 	if err != nil {
 		return nil, err
 	}
+	extraLabels := options.ExtraLabels
+	if extraLabels == nil {
+		extraLabels = map[string]string{}
+	}
+	percentileInterfaces := map[string]struct{}{}
+	for _, ifName := range options.BillingPercentile {
+		percentileInterfaces[ifName] = struct{}{}
+	}
 
-	return &MetricCollector{selector, sum, sumologic, stateFile, maxRateIntervalSeconds}, nil
+	return &MetricCollector{
+		selector:               selector,
+		sum:                    options.Sum,
+		sumologic:              options.SumoLogic || options.SumoLogicFieldNames,
+		sumoFieldNames:         options.SumoLogicFieldNames,
+		stateFile:              options.StateFile,
+		maxRateIntervalSeconds: options.MaxRateIntervalSeconds,
+		maxInterfaces:          options.MaxInterfaces,
+		naming:                 options.Naming,
+		metricPrefix:           options.MetricPrefix,
+		extraLabels:            extraLabels,
+		metricFilter:           filter,
+		rateUnit:               options.RateUnit,
+		thresholds:             thresholds,
+		deltaMetrics:           options.DeltaMetrics,
+		lifetime:               options.LifetimeCounters,
+		traffic:                options.TrafficAccounting || len(trafficQuotas) > 0,
+		trafficLocation:        trafficLocation,
+		trafficQuotas:          trafficQuotas,
+		quotaWarning:           options.TrafficQuotaWarning,
+		quotaCritical:          options.TrafficQuotaCritical,
+		percentileInterfaces:   percentileInterfaces,
+		anomalyZScore:          options.AnomalyZScore,
+		anomalyAlpha:           options.AnomalyAlpha,
+		anomalyWarmup:          options.AnomalyWarmup,
+		alertRaiseAfter:        options.AlertRaiseAfter,
+		alertClearAfter:        options.AlertClearAfter,
+		idle:                   options.IdleDetection || options.IdleThreshold > 0,
+		idleThreshold:          options.IdleThreshold,
+		interfaceUp:            options.InterfaceUp,
+		bootID:                 options.BootID,
+	}, nil
 }
 
 func (c *MetricCollector) Collect(netStatsGetter func(*selector) (NetStats, error)) ([]*dto.MetricFamily, error) {
@@ -61,10 +208,16 @@ func (c *MetricCollector) Collect(netStatsGetter func(*selector) (NetStats, erro
 		return nil, fmt.Errorf("couldn't get netstats: %w", err)
 	}
 
+	if c.memoryState != nil {
+		c.memoryState.SetBootID(c.bootID)
+		return c.generatePromMetrics(stats, c.memoryState), nil
+	}
+
 	metricState, err := metric.NewFromFile(c.stateFile)
 	if err != nil {
 		return nil, fmt.Errorf("error opening metric file %s", c.stateFile)
 	}
+	metricState.SetBootID(c.bootID)
 
 	families := c.generatePromMetrics(stats, metricState)
 
@@ -86,14 +239,22 @@ func (c *MetricCollector) Collect(netStatsGetter func(*selector) (NetStats, erro
 }
 
 func (c *MetricCollector) generatePromMetrics(stats NetStats, metricState *metric.CounterMetricState) []*dto.MetricFamily {
-	var sumo_family *dto.MetricFamily
 	families := make([]*dto.MetricFamily, 0)
+	lifetimeFamilies := make([]*dto.MetricFamily, 0)
+	c.alerts = make([]Alert, 0)
+	c.createdMS = map[*dto.Metric]int64{}
+	c.deltas = map[*dto.Metric]float64{}
+	c.anomalies = map[*dto.Metric]anomaly{}
 	nowMS := time.Now().UnixMilli()
-	metricType := "host_net"
-	help := metricHelp[metricType]
-	if c.sumologic {
-		sumo_family = newMetricFamily(metricType, help, dto.MetricType_COUNTER)
-		families = append(families, sumo_family)
+	deltaIntervals := map[string]float64{}
+	windows := map[string]map[string]*metric.SampleWindow{}
+	idleSeconds := map[string]map[string]float64{}
+	billingStartMS := c.billingPeriodStart(nowMS)
+	if c.keepsState() {
+		metricState.PruneWindows(billingStartMS)
+	}
+	if c.traffic && c.keepsState() {
+		metricState.PruneTraffic(time.UnixMilli(nowMS).In(c.trafficLocation))
 	}
 	for metricType, typeStats := range stats {
 		help := metricHelp[metricType]
@@ -108,45 +269,215 @@ func (c *MetricCollector) generatePromMetrics(stats NetStats, metricState *metri
 		if rateHelp == "" {
 			rateHelp = fmt.Sprintf("Network interface %s per second.", metricType)
 		}
-		rateFamily := newMetricFamily(rateMetricType, rateHelp, dto.MetricType_GAUGE)
+		rateFamily := newMetricFamily(rateMetricType, c.rateHelp(metricType, rateHelp), dto.MetricType_GAUGE)
+		rateScale := c.rateScale(metricType)
+		deltaFamily := newMetricFamily(metricType+deltaFamilySuffix, help+" since the previous sample", dto.MetricType_GAUGE)
+		lifetimeFamily := newMetricFamily(metricType+lifetimeFamilySuffix, help+" since the state was created",
+			dto.MetricType_COUNTER)
 
 		var total float64 = 0
 		var rateTotal float64 = 0
+		var deltaTotal float64 = 0
+		var lifetimeTotal float64 = 0
 		hasRate := false
+		hasDelta := false
+		_, billed := bitsRateFamilies[metricType]
+		billed = billed && len(c.percentileInterfaces) > 0 && c.keepsState()
+		if billed {
+			windows[metricType] = map[string]*metric.SampleWindow{}
+		}
+		sumFromMS := nowMS
+		_, idle := idleFamilies[metricType]
+		if idle && c.idle && c.keepsState() {
+			idleSeconds[metricType] = map[string]float64{}
+		}
 
 		for netIF, ifValue := range typeStats {
 			counter := newCounterMetric(family, netIF, ifValue, nowMS)
-			if c.sumologic {
-				_ = newSumoCounterMetric(sumo_family, metricType, netIF, ifValue, nowMS)
-			}
 			found, prevValue, prevTimestampMS := metricState.GetMetric(family, counter)
 			metricState.AddMetric(family, counter)
+			if hasCreated, createdMS := metricState.GetCreated(family, counter); hasCreated && c.keepsState() {
+				c.createdMS[counter] = createdMS
+			}
+			if c.lifetime && c.keepsState() {
+				_, lifetime := metricState.GetLifetime(family, counter)
+				newCounterMetric(lifetimeFamily, netIF, lifetime, nowMS)
+				lifetimeTotal += lifetime
+			}
+			if idleSeconds[metricType] != nil && c.isUp(netIF) {
+				_, changedMS := metricState.GetChanged(family, counter)
+				idleSeconds[metricType][netIF] = float64(nowMS-changedMS) / 1000.0
+			}
 			total += ifValue
+			sampleFromMS, sample := nowMS, float64(0)
 
 			if found {
+				if metricState.Rebooted() {
+					// the counters restarted from zero since the previous value
+					prevValue = 0
+				}
+				delta := counterDelta(prevValue, ifValue)
+				c.deltas[counter] = delta
+				newGaugeMetric(deltaFamily, netIF, delta, nowMS)
+				deltaTotal += delta
+				hasDelta = true
+
 				intervalSeconds := float64(nowMS-prevTimestampMS) / 1000.0
+				if intervalSeconds > deltaIntervals[netIF] {
+					deltaIntervals[netIF] = intervalSeconds
+				}
+				sampleFromMS, sample = prevTimestampMS, delta
+				if prevTimestampMS < sumFromMS {
+					sumFromMS = prevTimestampMS
+				}
 				if intervalSeconds > 0 && (c.maxRateIntervalSeconds == 0 || intervalSeconds < float64(c.maxRateIntervalSeconds)) {
-					rate := float64(ifValue-prevValue) / intervalSeconds
-					newGaugeMetric(rateFamily, netIF, rate, nowMS)
+					rate := delta / intervalSeconds * rateScale
+					rateMetric := newGaugeMetric(rateFamily, netIF, rate, nowMS)
+					if c.anomalyZScore > 0 {
+						c.addToBaseline(metricState, family, counter, rateMetric, delta/intervalSeconds, rateScale)
+					}
 					rateTotal += rate
 					hasRate = true
 				}
 			}
+			if billed && c.billed(netIF) {
+				windows[metricType][netIF] = metricState.AddToWindow(family, counter, billingStartMS, sampleFromMS, nowMS,
+					sample)
+			}
 		}
 
 		if hasRate {
 			families = append(families, rateFamily)
 		}
+		if hasDelta && c.deltaMetrics {
+			families = append(families, deltaFamily)
+		}
+		if len(lifetimeFamily.Metric) > 0 {
+			lifetimeFamilies = append(lifetimeFamilies, lifetimeFamily)
+		}
 
 		if c.sum {
-			newCounterMetric(family, "all", total, nowMS)
+			sumCounter := newCounterMetric(family, sumInterface, total, nowMS)
+			if hasDelta {
+				c.deltas[sumCounter] = deltaTotal
+				newGaugeMetric(deltaFamily, sumInterface, deltaTotal, nowMS)
+			}
 			if hasRate {
-				newGaugeMetric(rateFamily, "all", rateTotal, nowMS)
+				newGaugeMetric(rateFamily, sumInterface, rateTotal, nowMS)
+			}
+			if len(lifetimeFamily.Metric) > 0 {
+				newCounterMetric(lifetimeFamily, sumInterface, lifetimeTotal, nowMS)
+			}
+			if len(idleSeconds[metricType]) > 0 {
+				sumSeconds := float64(-1)
+				for _, seconds := range idleSeconds[metricType] {
+					if sumSeconds < 0 || seconds < sumSeconds {
+						sumSeconds = seconds
+					}
+				}
+				idleSeconds[metricType][sumInterface] = sumSeconds
+			}
+			if billed && c.billed(sumInterface) {
+				windows[metricType][sumInterface] = metricState.AddToWindow(family, sumCounter, billingStartMS, sumFromMS,
+					nowMS, deltaTotal)
 			}
 		}
+
+		if hasRate {
+			if bitsFamily := c.newBitsRateFamily(metricType, rateFamily); bitsFamily != nil {
+				families = append(families, bitsFamily)
+			}
+		}
+	}
+
+	if c.deltaMetrics && len(deltaIntervals) > 0 {
+		intervalFamily := newMetricFamily(deltaIntervalFamily, deltaIntervalHelp, dto.MetricType_GAUGE)
+		for netIF, intervalSeconds := range deltaIntervals {
+			newGaugeMetric(intervalFamily, netIF, intervalSeconds, nowMS)
+		}
+		families = append(families, intervalFamily)
+	}
+
+	if c.traffic && c.keepsState() {
+		families = append(families, c.newTrafficFamilies(families, metricState, nowMS)...)
+	}
+
+	if c.sumologic {
+		families = append(families, c.newSumoFamily(families))
+	}
+	families = append(families, lifetimeFamilies...)
+
+	families = c.foldInterfaces(families, nowMS)
+	families = append(families, c.newRatioFamilies(families)...)
+	families = append(families, c.newPercentileFamilies(families, windows, nowMS)...)
+	families = append(families, c.newZScoreFamilies(families)...)
+	families = append(families, c.newIdleFamilies(families, idleSeconds, nowMS)...)
+	c.evaluateThresholds(families)
+	c.applyHysteresis(metricState)
+
+	nativeNames := map[*dto.MetricFamily]string{}
+	for _, family := range families {
+		nativeNames[family] = family.GetName()
+	}
+	families = c.filterFamilies(families)
+	families = c.renameFamilies(families)
+	families = c.applyPrefixAndLabels(families)
+	c.mapOutputThresholds(families, nativeNames)
+
+	return sortFamilies(families)
+}
+
+// counterDelta returns the increase of a counter since its previous value. A counter lower than its previous value
+// was reset, the increase is then its whole value.
+func counterDelta(prevValue, value float64) float64 {
+	if value < prevValue {
+		return value
+	}
+	return value - prevValue
+}
+
+// keepsState returns whether the counters are kept from one Collect to the next, in the state file or in memory.
+func (c *MetricCollector) keepsState() bool {
+	return c.stateFile != "" || c.memoryState != nil
+}
+
+// isUp returns whether the interface is up, all interfaces being up without an interface state getter.
+func (c *MetricCollector) isUp(ifName string) bool {
+	return c.interfaceUp == nil || c.interfaceUp(ifName)
+}
+
+// sortFamilies returns the families sorted by name, with the metrics of each family sorted by label set and the
+// interface="all" sum last.
+func sortFamilies(families []*dto.MetricFamily) []*dto.MetricFamily {
+	sorted := make([]*dto.MetricFamily, len(families))
+	copy(sorted, families)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].GetName() < sorted[j].GetName()
+	})
+
+	for _, family := range sorted {
+		metrics := family.Metric
+		sort.SliceStable(metrics, func(i, j int) bool {
+			iSum, jSum := metricInterface(metrics[i]) == sumInterface, metricInterface(metrics[j]) == sumInterface
+			if iSum != jSum {
+				return jSum
+			}
+			return sortedLabelsKey(metrics[i]) < sortedLabelsKey(metrics[j])
+		})
 	}
 
-	return families
+	return sorted
+}
+
+func sortedLabelsKey(m *dto.Metric) string {
+	var key strings.Builder
+	for _, label := range sortedLabels(m) {
+		key.WriteString(label.GetName())
+		key.WriteString("=")
+		key.WriteString(label.GetValue())
+		key.WriteString(",")
+	}
+	return key.String()
 }
 
 func newMetricFamily(name, help string, metricType dto.MetricType) *dto.MetricFamily {
@@ -172,22 +503,6 @@ func newCounterMetric(family *dto.MetricFamily, ifName string, value float64, ti
 
 	return counter
 }
-func newSumoCounterMetric(family *dto.MetricFamily, fieldName string, ifName string, value float64, timestampMS int64) *dto.Metric {
-	counter := &dto.Metric{
-		Label: []*dto.LabelPair{
-			{Name: &interfaceLabel, Value: &ifName},
-			{Name: &fieldLabel, Value: &fieldName},
-		},
-		Counter: &dto.Counter{
-			Value: &value,
-		},
-		TimestampMs: &timestampMS,
-	}
-	family.Metric = append(family.Metric, counter)
-
-	return counter
-}
-
 func newGaugeMetric(family *dto.MetricFamily, ifName string, value float64, timestampMS int64) *dto.Metric {
 	gauge := &dto.Metric{
 		Label: []*dto.LabelPair{{Name: &interfaceLabel, Value: &ifName}},
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/sensu/network-interface-checks/metric"
+	"github.com/sensu/sensu-plugin-sdk/sensu"
+)
+
+const (
+	zscoreFamilySuffix = "_zscore"
+	// minAnomalyStdDev is the lowest standard deviation of a baseline, in units per second, so that a single error
+	// on an interface without errors is not an anomaly.
+	minAnomalyStdDev = 1
+	// minAnomalyRelativeStdDev is the lowest standard deviation of a baseline relative to its mean.
+	minAnomalyRelativeStdDev = 0.1
+)
+
+// anomaly is the deviation of a rate from the baseline of its counter, the mean being scaled like the rate.
+type anomaly struct {
+	zscore float64
+	mean   float64
+}
+
+// addToBaseline adds the rate of the counter, in units per second, to its baseline and records the deviation of the
+// rate metric from the baseline once the baseline holds the warm-up number of rates. The standard deviation of the
+// baseline is at least minAnomalyStdDev and minAnomalyRelativeStdDev of the mean, so that the small variance of a
+// steady or idle rate doesn't turn every change into an anomaly.
+func (c *MetricCollector) addToBaseline(metricState *metric.CounterMetricState, family *dto.MetricFamily, counter,
+	rateMetric *dto.Metric, rate, rateScale float64) {
+	baseline := metricState.UpdateBaseline(family, counter, rate, c.anomalyAlpha)
+	if baseline.Samples < c.anomalyWarmup {
+		return
+	}
+	minStdDev := math.Max(minAnomalyStdDev, minAnomalyRelativeStdDev*math.Abs(baseline.Mean))
+	stdDev := math.Max(math.Sqrt(baseline.Variance), minStdDev)
+	c.anomalies[rateMetric] = anomaly{
+		zscore: (rate - baseline.Mean) / stdDev,
+		mean:   baseline.Mean * rateScale,
+	}
+}
+
+// newZScoreFamilies returns the z-score of the rates of each interface, e.g. bytes_recv_rate_zscore, and raises a
+// warning for each rate deviating from its baseline by more than the z-score threshold.
+func (c *MetricCollector) newZScoreFamilies(families []*dto.MetricFamily) []*dto.MetricFamily {
+	zscores := make([]*dto.MetricFamily, 0)
+	for _, family := range families {
+		if !strings.HasSuffix(family.GetName(), rateFamilySuffix) {
+			continue
+		}
+
+		zscoreFamily := newMetricFamily(family.GetName()+zscoreFamilySuffix,
+			"standard deviations of "+family.GetName()+" from its baseline", dto.MetricType_GAUGE)
+		for _, m := range family.Metric {
+			a, ok := c.anomalies[m]
+			if !ok {
+				continue
+			}
+			ifName, _ := getLabelValue(m, interfaceLabel)
+			newGaugeMetric(zscoreFamily, ifName, a.zscore, m.GetTimestampMs())
+			if math.Abs(a.zscore) <= c.anomalyZScore {
+				continue
+			}
+			c.alerts = append(c.alerts, Alert{
+				Status:    sensu.CheckStateWarning,
+				Rule:      "anomaly:" + family.GetName(),
+				Interface: ifName,
+				Message: fmt.Sprintf("%s on %s is %s, %s standard deviations from its baseline of %s", family.GetName(),
+					ifName, formatValue(getMetricValue(m)), strconv.FormatFloat(a.zscore, 'f', 1, 64), formatValue(a.mean)),
+			})
+		}
+		if len(zscoreFamily.Metric) > 0 {
+			zscores = append(zscores, zscoreFamily)
+		}
+	}
+
+	return zscores
+}
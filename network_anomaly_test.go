@@ -0,0 +1,89 @@
+package main
+
+import (
+	"testing"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/sensu/network-interface-checks/metric"
+	"github.com/sensu/sensu-plugin-sdk/sensu"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetricCollector_ZScoreFamilies(t *testing.T) {
+	collector := &MetricCollector{anomalyZScore: 3, anomalyAlpha: 0.5, anomalyWarmup: 2}
+	state := metric.New()
+	family := newMetricFamily(bytesRecvFamily, metricHelp[bytesRecvFamily], dto.MetricType_COUNTER)
+	rates := map[string][]float64{
+		// baseline of mean 15 and variance 12.5 before the last rate
+		"eno1": {10, 20, 15, 40},
+		// not warmed up
+		"eno2": {10, 20},
+		// no variance, the standard deviation is 10% of the mean
+		"eno3": {10, 10, 10, 20},
+		// within the threshold
+		"eno4": {10, 20, 15, 20},
+	}
+
+	var rateFamily *dto.MetricFamily
+	for run := 0; run < 4; run++ {
+		collector.anomalies = map[*dto.Metric]anomaly{}
+		rateFamily = newMetricFamily(bytesRecvFamily+rateFamilySuffix, metricHelp["bytes_recv_rate"], dto.MetricType_GAUGE)
+		for ifName, ifRates := range rates {
+			if run >= len(ifRates) {
+				continue
+			}
+			counter := newCounterMetric(family, ifName, float64(run), int64(run))
+			state.AddMetric(family, counter)
+			rateMetric := newGaugeMetric(rateFamily, ifName, ifRates[run]*2, int64(run))
+			collector.addToBaseline(state, family, counter, rateMetric, ifRates[run], 2)
+		}
+	}
+	newGaugeMetric(rateFamily, sumInterface, 120, 3)
+
+	collector.alerts = make([]Alert, 0)
+	zscores := collector.newZScoreFamilies([]*dto.MetricFamily{family, rateFamily})
+	assert.Len(t, zscores, 1)
+	assert.Equal(t, "bytes_recv_rate_zscore", zscores[0].GetName())
+	assert.Equal(t, "standard deviations of bytes_recv_rate from its baseline", zscores[0].GetHelp())
+	values := valuesByInterface(zscores[0])
+	assert.Len(t, values, 3)
+	assert.InDelta(t, 25/3.5355339, values["eno1"], 1e-6)
+	assert.InDelta(t, 10, values["eno3"], 1e-6)
+	assert.InDelta(t, 5/3.5355339, values["eno4"], 1e-6)
+
+	assert.Equal(t, []Alert{{
+		Status:    sensu.CheckStateWarning,
+		Rule:      "anomaly:bytes_recv_rate",
+		Interface: "eno1",
+		Message:   "bytes_recv_rate on eno1 is 80, 7.1 standard deviations from its baseline of 30",
+	}, {
+		Status:    sensu.CheckStateWarning,
+		Rule:      "anomaly:bytes_recv_rate",
+		Interface: "eno3",
+		Message:   "bytes_recv_rate on eno3 is 40, 10.0 standard deviations from its baseline of 20",
+	}}, collector.Alerts())
+}
+
+func TestMetricCollector_ZScoreZeroBaseline(t *testing.T) {
+	collector := &MetricCollector{anomalyZScore: 3, anomalyAlpha: 0.05, anomalyWarmup: 30,
+		anomalies: map[*dto.Metric]anomaly{}}
+	state := metric.New()
+	family := newMetricFamily("err_in", metricHelp["err_in"], dto.MetricType_COUNTER)
+	rateFamily := newMetricFamily("err_in"+rateFamilySuffix, metricHelp["err_in_rate"], dto.MetricType_GAUGE)
+	for run := 0; run < 60; run++ {
+		counter := newCounterMetric(family, "eno1", 0, int64(run))
+		state.AddMetric(family, counter)
+		collector.addToBaseline(state, family, counter, newGaugeMetric(rateFamily, "eno1", 0, int64(run)), 0, 1)
+	}
+	// a single error over a 60 second interval
+	counter := newCounterMetric(family, "eno1", 1, 60)
+	state.AddMetric(family, counter)
+	rateFamily = newMetricFamily("err_in"+rateFamilySuffix, metricHelp["err_in_rate"], dto.MetricType_GAUGE)
+	collector.addToBaseline(state, family, counter, newGaugeMetric(rateFamily, "eno1", 1.0/60, 60), 1.0/60, 1)
+
+	collector.alerts = make([]Alert, 0)
+	zscores := collector.newZScoreFamilies([]*dto.MetricFamily{family, rateFamily})
+	assert.Len(t, zscores, 1)
+	assert.InDelta(t, 1.0/60, valuesByInterface(zscores[0])["eno1"], 1e-9)
+	assert.Empty(t, collector.Alerts())
+}
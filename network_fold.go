@@ -0,0 +1,216 @@
+package main
+
+import (
+	"sort"
+	"strings"
+
+	dto "github.com/prometheus/client_model/go"
+)
+
+const (
+	otherInterface   = "other"
+	sumInterface     = "all"
+	foldedFamilyName = "interfaces_folded"
+	bytesRecvFamily  = "bytes_recv"
+	bytesSentFamily  = "bytes_sent"
+	rateFamilySuffix = "_rate"
+)
+
+// nonAdditiveFamilies lists the families whose values can't be summed across interfaces. The metrics of the folded
+// interfaces are dropped from these families instead of being folded into interface="other".
+var nonAdditiveFamilies = map[string]struct{}{
+	"mtu":               {},
+	deltaIntervalFamily: {},
+}
+
+func init() {
+	for _, ratio := range ratioFamilies {
+		nonAdditiveFamilies[ratio.name] = struct{}{}
+	}
+	for _, idle := range idleFamilies {
+		nonAdditiveFamilies[idle.name] = struct{}{}
+	}
+}
+
+// isAdditive returns whether the values of the family can be summed across interfaces.
+func isAdditive(name string) bool {
+	if _, ok := nonAdditiveFamilies[name]; ok {
+		return false
+	}
+	return !strings.HasSuffix(name, zscoreFamilySuffix) && !strings.HasSuffix(name, percentileFamilySuffix)
+}
+
+// foldInterfaces keeps the metrics of the maxInterfaces busiest interfaces and folds the metrics of every other
+// interface into a single interface="other" metric per family. A gauge with the number of folded interfaces is
+// appended to the families. The interface="other" counters are the sums of the counters of the interfaces folded by
+// this run, so they decrease when an interface leaves the folded set.
+func (c *MetricCollector) foldInterfaces(families []*dto.MetricFamily, nowMS int64) []*dto.MetricFamily {
+	if c.maxInterfaces <= 0 {
+		return families
+	}
+
+	ranking := rankInterfaces(families)
+	kept := map[string]struct{}{}
+	for i := 0; i < len(ranking) && i < c.maxInterfaces; i++ {
+		kept[ranking[i]] = struct{}{}
+	}
+
+	folded := 0
+	if len(ranking) > c.maxInterfaces {
+		folded = len(ranking) - c.maxInterfaces
+		for _, family := range families {
+			foldFamily(family, kept, c.deltas)
+		}
+	}
+
+	foldedFamily := newMetricFamily(foldedFamilyName, metricHelp[foldedFamilyName], dto.MetricType_GAUGE)
+	value := float64(folded)
+	foldedFamily.Metric = append(foldedFamily.Metric, &dto.Metric{
+		Gauge:       &dto.Gauge{Value: &value},
+		TimestampMs: &nowMS,
+	})
+
+	return append(families, foldedFamily)
+}
+
+// rankInterfaces returns the interface names found in the families, busiest first. Interfaces are ranked by their
+// received plus sent bytes rate, or by their bytes counters when no rate is available.
+func rankInterfaces(families []*dto.MetricFamily) []string {
+	familyMap := map[string]*dto.MetricFamily{}
+	interfaces := map[string]struct{}{}
+	for _, family := range families {
+		familyMap[family.GetName()] = family
+		for _, m := range family.GetMetric() {
+			if ifName, ok := getLabelValue(m, interfaceLabel); ok && ifName != sumInterface {
+				interfaces[ifName] = struct{}{}
+			}
+		}
+	}
+
+	rankFamilies := []string{bytesRecvFamily + rateFamilySuffix, bytesSentFamily + rateFamilySuffix}
+	if familyMap[rankFamilies[0]] == nil && familyMap[rankFamilies[1]] == nil {
+		rankFamilies = []string{bytesRecvFamily, bytesSentFamily}
+	}
+
+	scores := map[string]float64{}
+	for _, name := range rankFamilies {
+		for _, m := range familyMap[name].GetMetric() {
+			if ifName, ok := getLabelValue(m, interfaceLabel); ok {
+				scores[ifName] += getMetricValue(m)
+			}
+		}
+	}
+
+	ranking := make([]string, 0, len(interfaces))
+	for ifName := range interfaces {
+		ranking = append(ranking, ifName)
+	}
+	sort.Slice(ranking, func(i, j int) bool {
+		if scores[ranking[i]] != scores[ranking[j]] {
+			return scores[ranking[i]] > scores[ranking[j]]
+		}
+		return ranking[i] < ranking[j]
+	})
+
+	return ranking
+}
+
+// foldFamily replaces the metrics of the interfaces not present in kept by interface="other" metrics, summing the
+// values and the counter deltas of metrics that share the same remaining labels. The metrics of these interfaces are
+// dropped from the non-additive families.
+func foldFamily(family *dto.MetricFamily, kept map[string]struct{}, deltas map[*dto.Metric]float64) {
+	metrics := make([]*dto.Metric, 0, len(family.Metric))
+	others := map[string]*dto.Metric{}
+	additive := isAdditive(family.GetName())
+	for _, m := range family.Metric {
+		ifName, ok := getLabelValue(m, interfaceLabel)
+		if _, isKept := kept[ifName]; !ok || isKept || ifName == sumInterface {
+			metrics = append(metrics, m)
+			continue
+		}
+		if !additive {
+			continue
+		}
+
+		other := newOtherMetric(m)
+		key := labelsKey(other)
+		if existing, ok := others[key]; ok {
+			other = existing
+			addMetricValue(other, getMetricValue(m))
+		} else {
+			others[key] = other
+			metrics = append(metrics, other)
+		}
+		if delta, ok := deltas[m]; ok {
+			deltas[other] += delta
+		}
+	}
+	family.Metric = metrics
+}
+
+// newOtherMetric returns a copy of the metric with its interface label set to "other".
+func newOtherMetric(m *dto.Metric) *dto.Metric {
+	other := &dto.Metric{
+		Label:       make([]*dto.LabelPair, 0, len(m.Label)),
+		TimestampMs: m.TimestampMs,
+	}
+	for _, label := range m.Label {
+		if label.GetName() == interfaceLabel {
+			ifName := otherInterface
+			label = &dto.LabelPair{Name: label.Name, Value: &ifName}
+		}
+		other.Label = append(other.Label, label)
+	}
+
+	value := getMetricValue(m)
+	switch {
+	case m.Counter != nil:
+		other.Counter = &dto.Counter{Value: &value}
+	case m.Untyped != nil:
+		other.Untyped = &dto.Untyped{Value: &value}
+	default:
+		other.Gauge = &dto.Gauge{Value: &value}
+	}
+
+	return other
+}
+
+func getLabelValue(m *dto.Metric, name string) (string, bool) {
+	for _, label := range m.GetLabel() {
+		if label.GetName() == name {
+			return label.GetValue(), true
+		}
+	}
+	return "", false
+}
+
+func getMetricValue(m *dto.Metric) float64 {
+	switch {
+	case m.Counter != nil:
+		return m.Counter.GetValue()
+	case m.Untyped != nil:
+		return m.Untyped.GetValue()
+	default:
+		return m.GetGauge().GetValue()
+	}
+}
+
+func addMetricValue(m *dto.Metric, value float64) {
+	value += getMetricValue(m)
+	switch {
+	case m.Counter != nil:
+		m.Counter.Value = &value
+	case m.Untyped != nil:
+		m.Untyped.Value = &value
+	default:
+		m.Gauge.Value = &value
+	}
+}
+
+func labelsKey(m *dto.Metric) string {
+	key := ""
+	for _, label := range m.GetLabel() {
+		key += label.GetName() + "=" + label.GetValue() + ","
+	}
+	return key
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+)
+
+func GetNetStatsFoldMock1(_ *selector) (NetStats, error) {
+	return NetStats{
+		"bytes_recv": map[string]float64{
+			"eno1":  1000,
+			"eno2":  5000,
+			"veth1": 200,
+			"veth2": 100,
+		}, "bytes_sent": map[string]float64{
+			"eno1":  1000,
+			"eno2":  5000,
+			"veth1": 100,
+			"veth2": 50,
+		},
+	}, nil
+}
+
+func GetNetStatsFoldMock2(_ *selector) (NetStats, error) {
+	return NetStats{
+		"bytes_recv": map[string]float64{
+			"eno1":  9000,
+			"eno2":  5100,
+			"veth1": 300,
+			"veth2": 150,
+		}, "bytes_sent": map[string]float64{
+			"eno1":  9000,
+			"eno2":  5100,
+			"veth1": 200,
+			"veth2": 100,
+		},
+	}, nil
+}
+
+func TestMetricCollector_FoldInterfaces(t *testing.T) {
+	tmpFile := filepath.Join(t.TempDir(), uuid.New().String()+".json")
+
+	// First run ranks by counters: eno2 is the busiest
+	collector, err := NewCollector(CollectorOptions{Sum: true, StateFile: tmpFile, MaxRateIntervalSeconds: 60, MaxInterfaces: 1})
+	assert.NoError(t, err)
+	families, err := collector.Collect(GetNetStatsFoldMock1)
+	assert.NoError(t, err)
+	familyMap := familiesByName(families)
+	assert.Contains(t, familyMap, foldedFamilyName)
+	assert.Equal(t, float64(3), familyMap[foldedFamilyName].Metric[0].GetGauge().GetValue())
+	assert.Equal(t, map[string]float64{"eno2": 5000, "other": 1300, "all": 6300}, valuesByInterface(familyMap["bytes_recv"]))
+	assert.Equal(t, map[string]float64{"eno2": 5000, "other": 1150, "all": 6150}, valuesByInterface(familyMap["bytes_sent"]))
+
+	// small sleep to ensure second call to Collect has different timestamp
+	time.Sleep(10 * time.Millisecond)
+
+	// Second run ranks by rates: eno1 is the busiest, and rates of folded interfaces are preserved in "other"
+	collector, err = NewCollector(CollectorOptions{StateFile: tmpFile, MaxRateIntervalSeconds: 60, MaxInterfaces: 2})
+	assert.NoError(t, err)
+	families, err = collector.Collect(GetNetStatsFoldMock2)
+	assert.NoError(t, err)
+	familyMap = familiesByName(families)
+	assert.Equal(t, float64(2), familyMap[foldedFamilyName].Metric[0].GetGauge().GetValue())
+	assert.Equal(t, map[string]float64{"eno1": 9000, "eno2": 5100, "other": 450}, valuesByInterface(familyMap["bytes_recv"]))
+	assert.Len(t, familyMap["bytes_recv_rate"].Metric, 3)
+	assert.Contains(t, valuesByInterface(familyMap["bytes_recv_rate"]), "other")
+
+	// counter deltas of folded interfaces are summed too
+	deltas := map[string]float64{}
+	for _, m := range familyMap["bytes_recv"].Metric {
+		deltas[metricInterface(m)] = collector.deltas[m]
+	}
+	assert.Equal(t, map[string]float64{"eno1": 8000, "eno2": 100, "other": 150}, deltas)
+}
+
+func TestMetricCollector_FoldInterfacesDisabled(t *testing.T) {
+	collector, err := NewCollector(CollectorOptions{MaxRateIntervalSeconds: 60})
+	assert.NoError(t, err)
+	families, err := collector.Collect(GetNetStatsFoldMock1)
+	assert.NoError(t, err)
+	familyMap := familiesByName(families)
+	assert.NotContains(t, familyMap, foldedFamilyName)
+	assert.Len(t, familyMap["bytes_recv"].Metric, 4)
+
+	// fewer interfaces than the maximum, nothing folded
+	collector, err = NewCollector(CollectorOptions{MaxRateIntervalSeconds: 60, MaxInterfaces: 10})
+	assert.NoError(t, err)
+	families, err = collector.Collect(GetNetStatsFoldMock1)
+	assert.NoError(t, err)
+	familyMap = familiesByName(families)
+	assert.Equal(t, float64(0), familyMap[foldedFamilyName].Metric[0].GetGauge().GetValue())
+	assert.Len(t, familyMap["bytes_recv"].Metric, 4)
+}
+
+func TestFoldFamily_NonAdditive(t *testing.T) {
+	kept := map[string]struct{}{"eno1": {}}
+	for _, name := range []string{"mtu", "err_in_ratio", "seconds_since_last_rx", "bytes_recv_rate_zscore",
+		"bytes_recv_rate_p95"} {
+		family := newMetricFamily(name, "", dto.MetricType_GAUGE)
+		for _, ifName := range []string{"eno1", "veth1", "veth2", sumInterface} {
+			newGaugeMetric(family, ifName, 1500, 1000)
+		}
+		foldFamily(family, kept, map[*dto.Metric]float64{})
+		assert.Equal(t, map[string]float64{"eno1": 1500, "all": 1500}, valuesByInterface(family), name)
+	}
+
+	family := newMetricFamily("traffic_today_bytes", "", dto.MetricType_GAUGE)
+	for _, ifName := range []string{"eno1", "veth1", "veth2"} {
+		newGaugeMetric(family, ifName, 1500, 1000)
+	}
+	foldFamily(family, kept, map[*dto.Metric]float64{})
+	assert.Equal(t, map[string]float64{"eno1": 1500, "other": 3000}, valuesByInterface(family))
+}
+
+func TestRankInterfaces(t *testing.T) {
+	collector, err := NewCollector(CollectorOptions{Sum: true, MaxRateIntervalSeconds: 60})
+	assert.NoError(t, err)
+	families, err := collector.Collect(GetNetStatsFoldMock1)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"eno2", "eno1", "veth1", "veth2"}, rankInterfaces(families))
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"github.com/sensu/network-interface-checks/metric"
+)
+
+// applyHysteresis replaces the alerts breached by the current run with the alerts raised once breached by
+// alertRaiseAfter consecutive runs, until not breached by alertClearAfter consecutive runs. The consecutive runs are
+// counted per rule and interface in the state.
+func (c *MetricCollector) applyHysteresis(metricState *metric.CounterMetricState) {
+	if !c.keepsState() || (c.alertRaiseAfter <= 1 && c.alertClearAfter <= 1) {
+		return
+	}
+
+	breaches := make([]metric.AlertState, 0, len(c.alerts))
+	for _, alert := range c.alerts {
+		breaches = append(breaches, metric.AlertState{
+			Status:    alert.Status,
+			Rule:      alert.Rule,
+			Interface: alert.Interface,
+			Message:   alert.Message,
+		})
+	}
+
+	raised := metricState.UpdateAlerts(breaches, c.alertRaiseAfter, c.alertClearAfter)
+	c.alerts = make([]Alert, 0, len(raised))
+	for _, alert := range raised {
+		c.alerts = append(c.alerts, Alert{
+			Status:    alert.Status,
+			Rule:      alert.Rule,
+			Interface: alert.Interface,
+			Message:   alert.Message,
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/sensu/sensu-plugin-sdk/sensu"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetricCollector_Hysteresis(t *testing.T) {
+	tmpFile := filepath.Join(t.TempDir(), uuid.New().String()+".json")
+	newHysteresisCollector := func(stateFile string, warning map[string]string) *MetricCollector {
+		collector, err := NewCollector(CollectorOptions{
+			StateFile:              stateFile,
+			MaxRateIntervalSeconds: 60,
+			Warning:                warning,
+			AlertRaiseAfter:        2,
+			AlertClearAfter:        2,
+		})
+		assert.NoError(t, err)
+		return collector
+	}
+	breached := map[string]string{"err_in": "3"}
+	alert := Alert{Status: sensu.CheckStateWarning, Rule: "threshold:err_in", Interface: "eno2", Message: "err_in on eno2 is 4 (>= 3)"}
+
+	// raised by the second consecutive breach
+	collector := newHysteresisCollector(tmpFile, breached)
+	_, err := collector.Collect(GetNetStatsMock1)
+	assert.NoError(t, err)
+	assert.Equal(t, sensu.CheckStateOK, collector.Status())
+	collector = newHysteresisCollector(tmpFile, breached)
+	_, err = collector.Collect(GetNetStatsMock1)
+	assert.NoError(t, err)
+	assert.Equal(t, []Alert{alert}, collector.Alerts())
+
+	// kept by the first clean run, cleared by the second
+	collector = newHysteresisCollector(tmpFile, map[string]string{})
+	_, err = collector.Collect(GetNetStatsMock1)
+	assert.NoError(t, err)
+	assert.Equal(t, []Alert{alert}, collector.Alerts())
+	collector = newHysteresisCollector(tmpFile, map[string]string{})
+	_, err = collector.Collect(GetNetStatsMock1)
+	assert.NoError(t, err)
+	assert.Empty(t, collector.Alerts())
+
+	// without state the alerts are raised and cleared right away
+	collector = newHysteresisCollector("", breached)
+	_, err = collector.Collect(GetNetStatsMock1)
+	assert.NoError(t, err)
+	assert.Equal(t, []Alert{alert}, collector.Alerts())
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/sensu/sensu-plugin-sdk/sensu"
+)
+
+// idleFamilies maps the packets counters to the family of the seconds since they last changed
+var idleFamilies = map[string]struct {
+	name      string
+	help      string
+	direction string
+}{
+	"packets_recv": {"seconds_since_last_rx", "seconds since the interface last received a packet", "received"},
+	"packets_sent": {"seconds_since_last_tx", "seconds since the interface last sent a packet", "sent"},
+}
+
+// newIdleFamilies returns the seconds since the packets counters of each interface last changed, e.g.
+// seconds_since_last_rx, for the interfaces of the counter families, the interface="all" sum being the seconds since
+// any interface changed. An alert is raised for each interface idle for the idle threshold or longer.
+func (c *MetricCollector) newIdleFamilies(families []*dto.MetricFamily, idleSeconds map[string]map[string]float64,
+	nowMS int64) []*dto.MetricFamily {
+	idle := make([]*dto.MetricFamily, 0)
+	for _, family := range families {
+		idleFamily, ok := idleFamilies[family.GetName()]
+		if !ok || idleSeconds[family.GetName()] == nil {
+			continue
+		}
+
+		secondsFamily := newMetricFamily(idleFamily.name, idleFamily.help, dto.MetricType_GAUGE)
+		for _, m := range family.Metric {
+			ifName, _ := getLabelValue(m, interfaceLabel)
+			seconds, ok := idleSeconds[family.GetName()][ifName]
+			if !ok {
+				continue
+			}
+			newGaugeMetric(secondsFamily, ifName, seconds, nowMS)
+			if c.idleThreshold <= 0 || seconds < float64(c.idleThreshold) {
+				continue
+			}
+			c.alerts = append(c.alerts, Alert{
+				Status:    sensu.CheckStateWarning,
+				Rule:      "idle:" + idleFamily.name,
+				Interface: ifName,
+				Message: fmt.Sprintf("%s %s no packet for %s seconds (>= %d)", ifName, idleFamily.direction,
+					formatValue(seconds), c.idleThreshold),
+			})
+		}
+		if len(secondsFamily.Metric) > 0 {
+			idle = append(idle, secondsFamily)
+		}
+	}
+
+	return idle
+}
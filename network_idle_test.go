@@ -0,0 +1,83 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/sensu/sensu-plugin-sdk/sensu"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetricCollector_Idle(t *testing.T) {
+	tmpFile := filepath.Join(t.TempDir(), uuid.New().String()+".json")
+	newIdleCollector := func(stateFile string) *MetricCollector {
+		collector, err := NewCollector(CollectorOptions{Sum: true, StateFile: stateFile, MaxRateIntervalSeconds: 60, IdleDetection: true})
+		assert.NoError(t, err)
+		return collector
+	}
+
+	families, err := newIdleCollector(tmpFile).Collect(GetNetStatsRatioMock1)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]float64{"eno1": 0, "eno2": 0, "lo": 0, "all": 0},
+		valuesByInterface(familiesByName(families)["seconds_since_last_rx"]))
+
+	time.Sleep(20 * time.Millisecond)
+	families, err = newIdleCollector(tmpFile).Collect(GetNetStatsRatioMock2)
+	assert.NoError(t, err)
+	familyMap := familiesByName(families)
+	assert.Nil(t, familyMap["seconds_since_last_tx"])
+	assert.Equal(t, "seconds since the interface last received a packet", familyMap["seconds_since_last_rx"].GetHelp())
+	values := valuesByInterface(familyMap["seconds_since_last_rx"])
+	assert.Equal(t, float64(0), values["eno1"])
+	assert.Equal(t, float64(0), values["lo"])
+	assert.GreaterOrEqual(t, values["eno2"], 0.02)
+	assert.Equal(t, float64(0), values["all"])
+
+	// interfaces that are not up are not idle
+	collector, err := NewCollector(CollectorOptions{Sum: true, StateFile: tmpFile, MaxRateIntervalSeconds: 60,
+		IdleDetection: true, InterfaceUp: func(ifName string) bool { return ifName != "eno2" }})
+	assert.NoError(t, err)
+	families, err = collector.Collect(GetNetStatsRatioMock2)
+	assert.NoError(t, err)
+	values = valuesByInterface(familiesByName(families)["seconds_since_last_rx"])
+	assert.NotContains(t, values, "eno2")
+	assert.Len(t, values, 3)
+
+	// without state the idle time is unknown
+	collector = newIdleCollector("")
+	families, err = collector.Collect(GetNetStatsRatioMock2)
+	assert.NoError(t, err)
+	assert.Nil(t, familiesByName(families)["seconds_since_last_rx"])
+}
+
+func TestMetricCollector_IdleFamilies(t *testing.T) {
+	collector := &MetricCollector{idleThreshold: 600, alerts: make([]Alert, 0)}
+	recv := newMetricFamily("packets_recv", metricHelp["packets_recv"], dto.MetricType_COUNTER)
+	sent := newMetricFamily("packets_sent", metricHelp["packets_sent"], dto.MetricType_COUNTER)
+	for _, ifName := range []string{"eno1", "eno2", "other"} {
+		newCounterMetric(recv, ifName, 0, 1000)
+		newCounterMetric(sent, ifName, 0, 1000)
+	}
+	idleSeconds := map[string]map[string]float64{
+		"packets_recv": {"eno1": 900, "eno2": 10, "eno3": 1200},
+		"packets_sent": {"eno1": 600, "eno2": 0},
+	}
+
+	idle := collector.newIdleFamilies([]*dto.MetricFamily{recv, sent}, idleSeconds, 1000)
+	assert.Len(t, idle, 2)
+	familyMap := familiesByName(idle)
+	// folded interfaces have no idle time
+	assert.Equal(t, map[string]float64{"eno1": 900, "eno2": 10}, valuesByInterface(familyMap["seconds_since_last_rx"]))
+	assert.Equal(t, map[string]float64{"eno1": 600, "eno2": 0}, valuesByInterface(familyMap["seconds_since_last_tx"]))
+
+	assert.Equal(t, sensu.CheckStateWarning, collector.Status())
+	assert.Equal(t, []Alert{
+		{Status: sensu.CheckStateWarning, Rule: "idle:seconds_since_last_rx", Interface: "eno1",
+			Message: "eno1 received no packet for 900 seconds (>= 600)"},
+		{Status: sensu.CheckStateWarning, Rule: "idle:seconds_since_last_tx", Interface: "eno1",
+			Message: "eno1 sent no packet for 600 seconds (>= 600)"},
+	}, collector.Alerts())
+}
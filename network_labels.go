@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+
+	dto "github.com/prometheus/client_model/go"
+	v2 "github.com/sensu/sensu-go/api/core/v2"
+)
+
+var (
+	labelNameRE        = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+	metricPrefixRE     = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+	invalidLabelCharRE = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+	reservedLabels     = []string{interfaceLabel, fieldLabel, deviceLabel}
+
+	entityLabel    = "entity"
+	namespaceLabel = "namespace"
+)
+
+// validateLabels returns an error if a label name is invalid or clashes with the labels set by the plugin itself.
+func validateLabels(labels map[string]string) error {
+	for name := range labels {
+		if !labelNameRE.MatchString(name) {
+			return fmt.Errorf("invalid label name %q", name)
+		}
+		for _, reserved := range reservedLabels {
+			if name == reserved {
+				return fmt.Errorf("label name %q is reserved", name)
+			}
+		}
+	}
+	return nil
+}
+
+// validateEntityLabels returns an error if the label name an entity label is sanitized to is invalid, e.g. starts
+// with a digit, or clashes with the labels set by the plugin itself.
+func validateEntityLabels(names []string) error {
+	labels := map[string]string{}
+	for _, name := range names {
+		labels[entityLabelName(name)] = ""
+	}
+	return validateLabels(labels)
+}
+
+// entityLabelName returns the metric label name of an entity label, its invalid characters replaced with "_".
+func entityLabelName(name string) string {
+	return invalidLabelCharRE.ReplaceAllString(name, "_")
+}
+
+func validMetricPrefix(prefix string) bool {
+	return prefix == "" || metricPrefixRE.MatchString(prefix)
+}
+
+// entityLabels returns the labels taken from the entity of the event: the entity name and namespace when
+// withMetadata is set, and the value of each of the selected entity labels. Entity label names are sanitized to be
+// valid metric label names.
+func entityLabels(event *v2.Event, withMetadata bool, selected []string) map[string]string {
+	labels := map[string]string{}
+	if event == nil || event.Entity == nil {
+		return labels
+	}
+
+	if withMetadata {
+		labels[entityLabel] = event.Entity.Name
+		labels[namespaceLabel] = event.Entity.Namespace
+	}
+	for _, name := range selected {
+		if value, ok := event.Entity.Labels[name]; ok {
+			labels[entityLabelName(name)] = value
+		}
+	}
+
+	return labels
+}
+
+// applyPrefixAndLabels prefixes the family names with the configured metric prefix and adds the extra labels to
+// every metric. The Sumo Logic "host_net" family gets the extra labels but keeps its name.
+func (c *MetricCollector) applyPrefixAndLabels(families []*dto.MetricFamily) []*dto.MetricFamily {
+	names := make([]string, 0, len(c.extraLabels))
+	for name := range c.extraLabels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, family := range families {
+		if c.metricPrefix != "" && family.GetName() != sumoFamilyName {
+			name := c.metricPrefix + family.GetName()
+			family.Name = &name
+		}
+		for _, m := range family.Metric {
+			for _, name := range names {
+				name, value := name, c.extraLabels[name]
+				m.Label = append(m.Label, &dto.LabelPair{Name: &name, Value: &value})
+			}
+		}
+	}
+
+	return families
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"testing"
+
+	dto "github.com/prometheus/client_model/go"
+	v2 "github.com/sensu/sensu-go/api/core/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateLabels(t *testing.T) {
+	assert.NoError(t, validateLabels(map[string]string{}))
+	assert.NoError(t, validateLabels(map[string]string{"region": "us-west-2", "_tier": "edge"}))
+	assert.Error(t, validateLabels(map[string]string{"1region": "us-west-2"}))
+	assert.Error(t, validateLabels(map[string]string{"sensu.io/region": "us-west-2"}))
+	assert.Error(t, validateLabels(map[string]string{interfaceLabel: "eno1"}))
+	assert.Error(t, validateLabels(map[string]string{fieldLabel: "bytes_recv"}))
+}
+
+func TestValidateEntityLabels(t *testing.T) {
+	assert.NoError(t, validateEntityLabels([]string{}))
+	assert.NoError(t, validateEntityLabels([]string{"region", "app.kubernetes.io/name"}))
+	assert.Error(t, validateEntityLabels([]string{"1region"}))
+	assert.Error(t, validateEntityLabels([]string{"9.example.com/tier"}))
+	assert.Error(t, validateEntityLabels([]string{interfaceLabel}))
+	assert.Error(t, validateEntityLabels([]string{fieldLabel}))
+}
+
+func TestValidMetricPrefix(t *testing.T) {
+	assert.True(t, validMetricPrefix(""))
+	assert.True(t, validMetricPrefix("sensu_net_"))
+	assert.True(t, validMetricPrefix("sensu:"))
+	assert.False(t, validMetricPrefix("0sensu"))
+	assert.False(t, validMetricPrefix("sensu-net"))
+}
+
+func TestEntityLabels(t *testing.T) {
+	event := v2.FixtureEvent("host1", "network-interface-checks")
+	event.Entity.Namespace = "prod"
+	event.Entity.Labels = map[string]string{"region": "us-west-2", "app.kubernetes.io/name": "edge"}
+
+	assert.Equal(t, map[string]string{}, entityLabels(nil, true, []string{"region"}))
+	assert.Equal(t, map[string]string{}, entityLabels(event, false, []string{}))
+	assert.Equal(t, map[string]string{"entity": "host1", "namespace": "prod"}, entityLabels(event, true, []string{}))
+	assert.Equal(t, map[string]string{"region": "us-west-2", "app_kubernetes_io_name": "edge"},
+		entityLabels(event, false, []string{"region", "app.kubernetes.io/name", "missing"}))
+}
+
+func TestMetricCollector_PrefixAndLabels(t *testing.T) {
+	collector, err := NewCollector(CollectorOptions{
+		Sum:                    true,
+		SumoLogic:              true,
+		MaxRateIntervalSeconds: 60,
+		MetricPrefix:           "sensu_net_",
+		ExtraLabels:            map[string]string{"region": "us-west-2", "entity": "host1"},
+	})
+	assert.NoError(t, err)
+	families, err := collector.Collect(GetNetStatsMock1)
+	assert.NoError(t, err)
+	familyMap := familiesByName(families)
+	assert.Contains(t, familyMap, "sensu_net_bytes_sent")
+	assert.Contains(t, familyMap, "sensu_net_err_in")
+	assert.Contains(t, familyMap, sumoFamilyName)
+	for _, family := range families {
+		for _, metric := range family.Metric {
+			assertLabel(t, metric, "region", "us-west-2")
+			assertLabel(t, metric, "entity", "host1")
+		}
+	}
+	assert.True(t, hasSumMetric(familyMap["sensu_net_bytes_sent"]))
+}
+
+func assertLabel(t *testing.T, metric *dto.Metric, name, expected string) {
+	value, ok := getLabelValue(metric, name)
+	assert.True(t, ok)
+	assert.Equal(t, expected, value)
+}
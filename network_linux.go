@@ -8,12 +8,15 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
 	"regexp"
 	"strconv"
 	"strings"
 )
 
 var (
+	sysClassNetPath = "/sys/class/net"
+
 	procNetDevInterfaceRE = regexp.MustCompile(`^(.+): *(.+)$`)
 	procNetDevFieldSep    = regexp.MustCompile(` +`)
 
@@ -30,6 +33,26 @@ func getLocalInterfaceName() string {
 	return "lo"
 }
 
+// getBootID returns the ID of the current boot of the host, or an empty string when it isn't available.
+func getBootID() string {
+	content, err := os.ReadFile("/proc/sys/kernel/random/boot_id")
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(content))
+}
+
+// isInterfaceUp returns whether the operational state of the interface is up. Interfaces whose state is unknown, such as
+// the loopback or tun interfaces, or can't be read are considered up.
+func isInterfaceUp(ifName string) bool {
+	content, err := os.ReadFile(filepath.Join(sysClassNetPath, ifName, "operstate"))
+	if err != nil {
+		return true
+	}
+	state := strings.TrimSpace(string(content))
+	return state == "up" || state == "unknown"
+}
+
 func GetNetStats(selector *selector) (NetStats, error) {
 	file, err := os.Open("/proc/net/dev")
 	if err != nil {
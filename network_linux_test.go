@@ -1,9 +1,12 @@
 package main
 
 import (
-	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+
+	"github.com/stretchr/testify/assert"
 )
 
 const (
@@ -111,3 +114,20 @@ func TestParseNetStats(t *testing.T) {
 		})
 	}
 }
+
+func TestIsInterfaceUp(t *testing.T) {
+	dir := t.TempDir()
+	defer func(path string) { sysClassNetPath = path }(sysClassNetPath)
+	sysClassNetPath = dir
+	for ifName, state := range map[string]string{"eno1": "up\n", "eno2": "down\n", "lo": "unknown\n", "wg0": "dormant\n"} {
+		assert.NoError(t, os.MkdirAll(filepath.Join(dir, ifName), 0755))
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, ifName, "operstate"), []byte(state), 0644))
+	}
+
+	assert.True(t, isInterfaceUp("eno1"))
+	assert.False(t, isInterfaceUp("eno2"))
+	assert.True(t, isInterfaceUp("lo"))
+	assert.False(t, isInterfaceUp("wg0"))
+	// the state of a missing interface can't be read
+	assert.True(t, isInterfaceUp("eno3"))
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	dto "github.com/prometheus/client_model/go"
+)
+
+type metricFilter struct {
+	includes []func(string) bool
+	excludes []func(string) bool
+}
+
+// NewMetricFilter creates a filter of metric family names. Patterns are shell globs (e.g. "bytes_*_rate") unless
+// enclosed in slashes, in which case they are regular expressions (e.g. "/^(bytes|packets)_recv$/"). They match the
+// default metric names, before --naming and --metric-prefix rename the families, e.g. "bytes_sent" and not
+// "node_network_transmit_bytes_total".
+func NewMetricFilter(includes, excludes []string) (*metricFilter, error) {
+	filter := &metricFilter{}
+	for _, include := range includes {
+		matcher, err := newNameMatcher(include)
+		if err != nil {
+			return nil, err
+		}
+		filter.includes = append(filter.includes, matcher)
+	}
+	for _, exclude := range excludes {
+		matcher, err := newNameMatcher(exclude)
+		if err != nil {
+			return nil, err
+		}
+		filter.excludes = append(filter.excludes, matcher)
+	}
+
+	return filter, nil
+}
+
+func newNameMatcher(pattern string) (func(string) bool, error) {
+	if len(pattern) > 1 && strings.HasPrefix(pattern, "/") && strings.HasSuffix(pattern, "/") {
+		re, err := regexp.Compile(pattern[1 : len(pattern)-1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid metric regular expression %q: %v", pattern, err)
+		}
+		return re.MatchString, nil
+	}
+
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, fmt.Errorf("invalid metric glob pattern %q: %v", pattern, err)
+	}
+	return func(name string) bool {
+		matched, _ := path.Match(pattern, name)
+		return matched
+	}, nil
+}
+
+// Ignored returns whether the metric family of the default name is filtered out.
+func (f *metricFilter) Ignored(name string) bool {
+	if f == nil {
+		return false
+	}
+	if len(f.includes) > 0 && !matchesAny(f.includes, name) {
+		return true
+	}
+	return matchesAny(f.excludes, name)
+}
+
+func matchesAny(matchers []func(string) bool, name string) bool {
+	for _, matches := range matchers {
+		if matches(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// filterFamilies removes the families ignored by the metric filter. The counters of the removed families are still
+// tracked in the metric state so rates are correct as soon as a family is enabled again.
+func (c *MetricCollector) filterFamilies(families []*dto.MetricFamily) []*dto.MetricFamily {
+	if c.metricFilter == nil {
+		return families
+	}
+
+	filtered := make([]*dto.MetricFamily, 0, len(families))
+	for _, family := range families {
+		if !c.metricFilter.Ignored(family.GetName()) {
+			filtered = append(filtered, family)
+		}
+	}
+
+	return filtered
+}
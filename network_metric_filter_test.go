@@ -0,0 +1,130 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewMetricFilter(t *testing.T) {
+	tests := []struct {
+		name      string
+		includes  []string
+		excludes  []string
+		expectErr bool
+	}{
+		{
+			name:      "empty includes and excludes",
+			includes:  []string{},
+			excludes:  []string{},
+			expectErr: false,
+		}, {
+			name:      "globs",
+			includes:  []string{"bytes_*"},
+			excludes:  []string{"*_rate"},
+			expectErr: false,
+		}, {
+			name:      "regular expressions",
+			includes:  []string{"/^bytes_(recv|sent)$/"},
+			excludes:  []string{},
+			expectErr: false,
+		}, {
+			name:      "invalid glob",
+			includes:  []string{"bytes_[recv"},
+			excludes:  []string{},
+			expectErr: true,
+		}, {
+			name:      "invalid regular expression",
+			includes:  []string{},
+			excludes:  []string{"/bytes_(recv/"},
+			expectErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			filter, err := NewMetricFilter(test.includes, test.excludes)
+			if test.expectErr {
+				assert.Error(t, err)
+				assert.Nil(t, filter)
+			} else {
+				assert.NoError(t, err)
+				assert.NotNil(t, filter)
+			}
+		})
+	}
+}
+
+func TestMetricFilter_Ignored(t *testing.T) {
+	var nilFilter *metricFilter
+	assert.False(t, nilFilter.Ignored("bytes_recv"))
+
+	filter, _ := NewMetricFilter([]string{"bytes_*", "/^err_(in|out)_rate$/"}, []string{"bytes_sent*"})
+	assert.False(t, filter.Ignored("bytes_recv"))
+	assert.False(t, filter.Ignored("bytes_recv_rate"))
+	assert.False(t, filter.Ignored("err_in_rate"))
+	assert.True(t, filter.Ignored("bytes_sent"))
+	assert.True(t, filter.Ignored("bytes_sent_rate"))
+	assert.True(t, filter.Ignored("err_in"))
+	assert.True(t, filter.Ignored("packets_recv"))
+
+	filter, _ = NewMetricFilter([]string{}, []string{"*_rate"})
+	assert.False(t, filter.Ignored("bytes_recv"))
+	assert.True(t, filter.Ignored("bytes_recv_rate"))
+}
+
+func TestMetricCollector_FilterFamilies(t *testing.T) {
+	tmpFile := filepath.Join(t.TempDir(), uuid.New().String()+".json")
+
+	// First run only emits the rate families, but still tracks the counters
+	collector, err := NewCollector(CollectorOptions{
+		SumoLogic:              true,
+		StateFile:              tmpFile,
+		MaxRateIntervalSeconds: 60,
+		IncludeMetrics:         []string{"*_rate"},
+	})
+	assert.NoError(t, err)
+	families, err := collector.Collect(GetNetStatsMock1)
+	assert.NoError(t, err)
+	assert.Len(t, families, 0)
+
+	// small sleep to ensure second call to Collect has different timestamp
+	time.Sleep(10 * time.Millisecond)
+
+	collector, err = NewCollector(CollectorOptions{
+		SumoLogic:              true,
+		StateFile:              tmpFile,
+		MaxRateIntervalSeconds: 60,
+		IncludeMetrics:         []string{"*_rate"},
+		ExcludeMetrics:         []string{"err_*"},
+	})
+	assert.NoError(t, err)
+	families, err = collector.Collect(GetNetStatsMock2)
+	assert.NoError(t, err)
+	familyMap := familiesByName(families)
+	assert.Len(t, familyMap, 2)
+	assert.Contains(t, familyMap, "bytes_sent_rate")
+	assert.Contains(t, familyMap, "bits_sent_rate")
+}
+
+func TestMetricCollector_FilterFamiliesNodeExporter(t *testing.T) {
+	// patterns match the default names
+	collector, err := NewCollector(CollectorOptions{Naming: namingNodeExporter, IncludeMetrics: []string{"bytes_*"}})
+	assert.NoError(t, err)
+	families, err := collector.Collect(GetNetStatsMock1)
+	assert.NoError(t, err)
+	familyMap := familiesByName(families)
+	assert.Len(t, familyMap, 1)
+	assert.Contains(t, familyMap, "node_network_transmit_bytes_total")
+
+	// not the node_exporter ones
+	collector, err = NewCollector(CollectorOptions{Naming: namingNodeExporter,
+		IncludeMetrics: []string{"node_network_*"}})
+	assert.NoError(t, err)
+	families, err = collector.Collect(GetNetStatsMock1)
+	assert.NoError(t, err)
+	assert.Empty(t, families)
+}
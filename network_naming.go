@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	dto "github.com/prometheus/client_model/go"
+)
+
+const (
+	namingDefault      = "default"
+	namingNodeExporter = "node-exporter"
+
+	nodeExporterPrefix = "node_network_"
+	pluginNamespace    = "network_interface_checks_"
+)
+
+var (
+	namingModes = []string{namingDefault, namingNodeExporter}
+
+	// nodeExporterNames maps the default metric names to the node_exporter netdev and netclass collector names
+	nodeExporterNames = map[string]string{
+		"bytes_recv":   "receive_bytes_total",
+		"bytes_sent":   "transmit_bytes_total",
+		"packets_recv": "receive_packets_total",
+		"packets_sent": "transmit_packets_total",
+		"err_in":       "receive_errs_total",
+		"err_out":      "transmit_errs_total",
+		"drop_in":      "receive_drop_total",
+		"drop_out":     "transmit_drop_total",
+		"mtu":          "mtu_bytes",
+	}
+	nodeExporterGauges = map[string]struct{}{
+		"mtu": {},
+	}
+	deviceLabel = "device"
+)
+
+func validNaming(naming string) bool {
+	for _, mode := range namingModes {
+		if naming == mode {
+			return true
+		}
+	}
+	return false
+}
+
+// renameFamilies applies the configured naming mode to the families. The Sumo Logic "host_net" family keeps its
+// own naming regardless of the mode.
+func (c *MetricCollector) renameFamilies(families []*dto.MetricFamily) []*dto.MetricFamily {
+	if c.naming != namingNodeExporter {
+		return families
+	}
+
+	for _, family := range families {
+		if family.GetName() == sumoFamilyName {
+			continue
+		}
+		renameNodeExporterFamily(family)
+	}
+
+	return families
+}
+
+// renameNodeExporterFamily renames the family and its interface label following the node_exporter conventions.
+// Counters and the MTU become node_network_* families, while rates and any other plugin specific family are moved
+// under the network_interface_checks_ namespace since node_exporter has no equivalent.
+func renameNodeExporterFamily(family *dto.MetricFamily) {
+	name := family.GetName()
+	help := family.GetHelp()
+	if nodeName, ok := nodeExporterNames[name]; ok {
+		help = fmt.Sprintf("Network device statistic %s.", strings.TrimSuffix(nodeName, "_total"))
+		name = nodeExporterPrefix + nodeName
+		if _, ok := nodeExporterGauges[family.GetName()]; ok {
+			metricType := dto.MetricType_GAUGE
+			family.Type = &metricType
+			for _, m := range family.Metric {
+				if m.Counter != nil {
+					m.Gauge = &dto.Gauge{Value: m.Counter.Value}
+					m.Counter = nil
+				}
+			}
+		}
+	} else if counterName := strings.TrimSuffix(name, rateFamilySuffix); counterName != name && nodeExporterNames[counterName] != "" {
+		name = pluginNamespace + strings.TrimSuffix(nodeExporterNames[counterName], "_total") + "_per_second"
+	} else if counterName := strings.TrimSuffix(name, deltaFamilySuffix); counterName != name && nodeExporterNames[counterName] != "" {
+		name = pluginNamespace + strings.TrimSuffix(nodeExporterNames[counterName], "_total") + deltaFamilySuffix
+	} else {
+		name = pluginNamespace + name
+	}
+	family.Name = &name
+	family.Help = &help
+
+	for _, m := range family.Metric {
+		for i, label := range m.Label {
+			if label.GetName() == interfaceLabel {
+				m.Label[i] = &dto.LabelPair{Name: &deviceLabel, Value: label.Value}
+			}
+		}
+	}
+}
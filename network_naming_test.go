@@ -0,0 +1,88 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetricCollector_NodeExporterNaming(t *testing.T) {
+	tmpFile := filepath.Join(t.TempDir(), uuid.New().String()+".json")
+
+	options := CollectorOptions{Sum: true, SumoLogic: true, StateFile: tmpFile, MaxRateIntervalSeconds: 60, Naming: namingNodeExporter,
+		DeltaMetrics: true}
+	collector, err := NewCollector(options)
+	assert.NoError(t, err)
+	_, err = collector.Collect(GetNetStatsMock1)
+	assert.NoError(t, err)
+
+	// small sleep to ensure second call to Collect has different timestamp
+	time.Sleep(10 * time.Millisecond)
+
+	collector, err = NewCollector(options)
+	assert.NoError(t, err)
+	families, err := collector.Collect(GetNetStatsMock2)
+	assert.NoError(t, err)
+	familyMap := familiesByName(families)
+	assert.Len(t, familyMap, 9)
+	assert.Contains(t, familyMap, "node_network_transmit_bytes_total")
+	assert.Contains(t, familyMap, "node_network_receive_errs_total")
+	assert.Contains(t, familyMap, "network_interface_checks_transmit_bytes_per_second")
+	assert.Contains(t, familyMap, "network_interface_checks_receive_errs_per_second")
+	assert.Contains(t, familyMap, "network_interface_checks_bits_sent_rate")
+	assert.Contains(t, familyMap, "network_interface_checks_transmit_bytes_delta")
+	assert.Contains(t, familyMap, "network_interface_checks_delta_interval_seconds")
+	assert.Contains(t, familyMap, sumoFamilyName)
+
+	family := familyMap["node_network_transmit_bytes_total"]
+	assert.Equal(t, "Network device statistic transmit_bytes.", family.GetHelp())
+	assert.Equal(t, dto.MetricType_COUNTER, family.GetType())
+	for _, metric := range family.Metric {
+		_, ok := getLabelValue(metric, deviceLabel)
+		assert.True(t, ok)
+		_, ok = getLabelValue(metric, interfaceLabel)
+		assert.False(t, ok)
+	}
+
+	// Sumo Logic family keeps the interface label
+	for _, metric := range familyMap[sumoFamilyName].Metric {
+		_, ok := getLabelValue(metric, interfaceLabel)
+		assert.True(t, ok)
+	}
+}
+
+func TestRenameNodeExporterFamily(t *testing.T) {
+	testCases := []struct {
+		name         string
+		metricType   dto.MetricType
+		expectedName string
+		expectedType dto.MetricType
+	}{
+		{"bytes_recv", dto.MetricType_COUNTER, "node_network_receive_bytes_total", dto.MetricType_COUNTER},
+		{"drop_out", dto.MetricType_COUNTER, "node_network_transmit_drop_total", dto.MetricType_COUNTER},
+		{"mtu", dto.MetricType_COUNTER, "node_network_mtu_bytes", dto.MetricType_GAUGE},
+		{"packets_recv_rate", dto.MetricType_GAUGE, "network_interface_checks_receive_packets_per_second", dto.MetricType_GAUGE},
+		{"interfaces_folded", dto.MetricType_GAUGE, "network_interface_checks_interfaces_folded", dto.MetricType_GAUGE},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			family := newMetricFamily(testCase.name, "help", testCase.metricType)
+			if testCase.metricType == dto.MetricType_COUNTER {
+				newCounterMetric(family, "eno1", 1500, 0)
+			} else {
+				newGaugeMetric(family, "eno1", 1500, 0)
+			}
+			renameNodeExporterFamily(family)
+			assert.Equal(t, testCase.expectedName, family.GetName())
+			assert.Equal(t, testCase.expectedType, family.GetType())
+			assert.Equal(t, float64(1500), getMetricValue(family.Metric[0]))
+			device, _ := getLabelValue(family.Metric[0], deviceLabel)
+			assert.Equal(t, "eno1", device)
+		})
+	}
+}
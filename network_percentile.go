@@ -0,0 +1,59 @@
+package main
+
+import (
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/sensu/network-interface-checks/metric"
+)
+
+const (
+	billingPercentile      = 95
+	percentileFamilySuffix = "_p95"
+)
+
+// billingPeriodStart returns the start of the billing period, the month, in the traffic time zone.
+func (c *MetricCollector) billingPeriodStart(nowMS int64) int64 {
+	now := time.UnixMilli(nowMS).In(c.trafficLocation)
+	return time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location()).UnixMilli()
+}
+
+// billed returns whether the interface keeps a sample window for the percentiles.
+func (c *MetricCollector) billed(ifName string) bool {
+	_, ok := c.percentileInterfaces[ifName]
+	return ok
+}
+
+// newPercentileFamilies returns the 95th percentile of the 5 minute rates of the bytes counters since the beginning of
+// the billing period, e.g. bytes_recv_rate_p95, for the interfaces of the counter families having a sample window.
+// The rates are expressed in the configured rate unit, like the rate families they are named after.
+func (c *MetricCollector) newPercentileFamilies(families []*dto.MetricFamily,
+	windows map[string]map[string]*metric.SampleWindow, nowMS int64) []*dto.MetricFamily {
+	percentiles := make([]*dto.MetricFamily, 0)
+	for _, family := range families {
+		metricType := family.GetName()
+		if _, ok := bitsRateFamilies[metricType]; !ok || windows[metricType] == nil {
+			continue
+		}
+
+		rateName := metricType + rateFamilySuffix
+		help := c.rateHelp(metricType, metricHelp[rateName]) + ", 95th percentile of the 5 minute samples of the month"
+		percentileFamily := newMetricFamily(rateName+percentileFamilySuffix, help, dto.MetricType_GAUGE)
+		scale := c.rateScale(metricType) * 1000 / metric.SlotMS
+		for _, m := range family.Metric {
+			ifName, _ := getLabelValue(m, interfaceLabel)
+			window := windows[metricType][ifName]
+			if window == nil {
+				continue
+			}
+			if value, ok := window.Percentile(billingPercentile, nowMS); ok {
+				newGaugeMetric(percentileFamily, ifName, value*scale, nowMS)
+			}
+		}
+		if len(percentileFamily.Metric) > 0 {
+			percentiles = append(percentiles, percentileFamily)
+		}
+	}
+
+	return percentiles
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/sensu/network-interface-checks/metric"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetricCollector_BillingPeriodStart(t *testing.T) {
+	location, err := time.LoadLocation("Europe/Paris")
+	assert.NoError(t, err)
+	collector := &MetricCollector{trafficLocation: location}
+
+	// 2024-03-31T23:30:00Z is already April in Paris
+	nowMS := time.Date(2024, 3, 31, 23, 30, 0, 0, time.UTC).UnixMilli()
+	assert.Equal(t, time.Date(2024, 4, 1, 0, 0, 0, 0, location).UnixMilli(), collector.billingPeriodStart(nowMS))
+
+	collector.trafficLocation = time.UTC
+	assert.Equal(t, time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC).UnixMilli(), collector.billingPeriodStart(nowMS))
+}
+
+func TestMetricCollector_PercentileFamilies(t *testing.T) {
+	collector := &MetricCollector{rateUnit: rateUnitMbit}
+	startMS := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC).UnixMilli()
+	nowMS := startMS + 100*metric.SlotMS + 1000
+	state := metric.New()
+
+	recv := newMetricFamily(bytesRecvFamily, metricHelp[bytesRecvFamily], dto.MetricType_COUNTER)
+	sent := newMetricFamily(bytesSentFamily, metricHelp[bytesSentFamily], dto.MetricType_COUNTER)
+	packets := newMetricFamily("packets_recv", metricHelp["packets_recv"], dto.MetricType_COUNTER)
+	windows := map[string]map[string]*metric.SampleWindow{bytesRecvFamily: {}, bytesSentFamily: {}}
+	for _, ifName := range []string{"eno1", "eno2"} {
+		counter := newCounterMetric(recv, ifName, 0, nowMS)
+		for slot := int64(0); slot < 100; slot++ {
+			// 1..100 MB per slot on eno1, twice as much on eno2
+			bytes := float64(slot+1) * 1e6
+			if ifName == "eno2" {
+				bytes *= 2
+			}
+			fromMS := startMS + slot*metric.SlotMS
+			windows[bytesRecvFamily][ifName] = state.AddToWindow(recv, counter, startMS, fromMS, fromMS+metric.SlotMS, bytes)
+		}
+		newCounterMetric(packets, ifName, 0, nowMS)
+	}
+	// not a single completed slot
+	counter := newCounterMetric(sent, "eno1", 0, nowMS)
+	windows[bytesSentFamily]["eno1"] = state.AddToWindow(sent, counter, startMS, nowMS-1000, nowMS, 1e6)
+	// folded interfaces have no window
+	newCounterMetric(recv, "other", 0, nowMS)
+
+	percentiles := collector.newPercentileFamilies([]*dto.MetricFamily{recv, sent, packets}, windows, nowMS)
+	assert.Len(t, percentiles, 1)
+	family := percentiles[0]
+	assert.Equal(t, "bytes_recv_rate_p95", family.GetName())
+	assert.Equal(t, dto.MetricType_GAUGE, family.GetType())
+	assert.Equal(t, "megabits received per second, 95th percentile of the 5 minute samples of the month", family.GetHelp())
+	// 95MB and 190MB over 300 seconds
+	values := valuesByInterface(family)
+	assert.Len(t, values, 2)
+	assert.InDelta(t, 95e6*8/1e6/300, values["eno1"], 1e-9)
+	assert.InDelta(t, 190e6*8/1e6/300, values["eno2"], 1e-9)
+}
+
+func TestMetricCollector_PercentileInterfaces(t *testing.T) {
+	tmpFile := filepath.Join(t.TempDir(), uuid.New().String()+".json")
+	collector, err := NewCollector(CollectorOptions{Sum: true, StateFile: tmpFile, MaxRateIntervalSeconds: 60,
+		BillingPercentile: []string{"eno1", sumInterface}})
+	assert.NoError(t, err)
+	_, err = collector.Collect(GetNetStatsMock1)
+	assert.NoError(t, err)
+
+	// only the selected interfaces keep a sample window
+	data, err := os.ReadFile(tmpFile)
+	assert.NoError(t, err)
+	var state struct {
+		Windows map[string]json.RawMessage `json:"windows"`
+	}
+	assert.NoError(t, json.Unmarshal(data, &state))
+	windows := make([]string, 0)
+	for key := range state.Windows {
+		windows = append(windows, key)
+	}
+	assert.ElementsMatch(t, []string{"bytes_sent-interface=eno1", "bytes_sent-interface=all"}, windows)
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"strings"
+
+	dto "github.com/prometheus/client_model/go"
+)
+
+const (
+	rateUnitBytes = "bytes"
+	rateUnitBits  = "bits"
+	rateUnitKbit  = "kbit"
+	rateUnitMbit  = "Mbit"
+)
+
+var (
+	rateUnitNames = []string{rateUnitBytes, rateUnitBits, rateUnitKbit, rateUnitMbit}
+
+	// rateUnits holds the factor converting bytes to the unit and the unit name used in help texts
+	rateUnits = map[string]struct {
+		factor float64
+		name   string
+	}{
+		rateUnitBytes: {1, "bytes"},
+		rateUnitBits:  {8, "bits"},
+		rateUnitKbit:  {8 / 1e3, "kilobits"},
+		rateUnitMbit:  {8 / 1e6, "megabits"},
+	}
+
+	// bitsRateFamilies maps the bytes counters to their derived bits per second family
+	bitsRateFamilies = map[string]string{
+		bytesRecvFamily: "bits_recv_rate",
+		bytesSentFamily: "bits_sent_rate",
+	}
+)
+
+func validRateUnit(unit string) bool {
+	_, ok := rateUnits[unit]
+	return ok
+}
+
+// rateScale returns the factor applied to the rates of the metric type, which is the configured rate unit factor
+// for byte counters and 1 for any other counter.
+func (c *MetricCollector) rateScale(metricType string) float64 {
+	if _, ok := bitsRateFamilies[metricType]; !ok || c.rateUnit == "" {
+		return 1
+	}
+	return rateUnits[c.rateUnit].factor
+}
+
+// rateHelp returns the help of the rate family of the metric type, expressed in the configured rate unit.
+func (c *MetricCollector) rateHelp(metricType, help string) string {
+	if _, ok := bitsRateFamilies[metricType]; !ok || c.rateUnit == "" {
+		return help
+	}
+	return rateUnits[c.rateUnit].name + strings.TrimPrefix(help, "bytes")
+}
+
+// newBitsRateFamily derives the bits per second family from the rate family of a bytes counter. It returns nil for
+// any other counter.
+func (c *MetricCollector) newBitsRateFamily(metricType string, rateFamily *dto.MetricFamily) *dto.MetricFamily {
+	name, ok := bitsRateFamilies[metricType]
+	if !ok {
+		return nil
+	}
+
+	factor := rateUnits[rateUnitBits].factor / c.rateScale(metricType)
+	family := newMetricFamily(name, metricHelp[name], dto.MetricType_GAUGE)
+	for _, m := range rateFamily.Metric {
+		ifName, _ := getLabelValue(m, interfaceLabel)
+		newGaugeMetric(family, ifName, m.GetGauge().GetValue()*factor, m.GetTimestampMs())
+	}
+
+	return family
+}
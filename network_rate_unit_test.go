@@ -0,0 +1,49 @@
+package main
+
+import (
+	"testing"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetricCollector_RateUnit(t *testing.T) {
+	testCases := []struct {
+		unit         string
+		expectedRate float64
+		expectedHelp string
+	}{
+		{rateUnitBytes, 1000000, "bytes sent per second"},
+		{rateUnitBits, 8000000, "bits sent per second"},
+		{rateUnitKbit, 8000, "kilobits sent per second"},
+		{rateUnitMbit, 8, "megabits sent per second"},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.unit, func(t *testing.T) {
+			collector, err := NewCollector(CollectorOptions{Sum: true, MaxRateIntervalSeconds: 60, RateUnit: testCase.unit})
+			assert.NoError(t, err)
+
+			assert.Equal(t, testCase.expectedHelp, collector.rateHelp(bytesSentFamily, metricHelp["bytes_sent_rate"]))
+			assert.Equal(t, "inbound errors per second", collector.rateHelp("err_in", metricHelp["err_in_rate"]))
+			assert.Equal(t, float64(1), collector.rateScale("err_in"))
+
+			rateFamily := newMetricFamily("bytes_sent_rate", "", dto.MetricType_GAUGE)
+			newGaugeMetric(rateFamily, "eno1", 1000000*collector.rateScale(bytesSentFamily), 0)
+			newGaugeMetric(rateFamily, sumInterface, 1000000*collector.rateScale(bytesSentFamily), 0)
+			assert.InDelta(t, testCase.expectedRate, rateFamily.Metric[0].GetGauge().GetValue(), 1e-9)
+
+			bitsFamily := collector.newBitsRateFamily(bytesSentFamily, rateFamily)
+			assert.Equal(t, "bits_sent_rate", bitsFamily.GetName())
+			assert.Equal(t, map[string]float64{"eno1": 8000000, sumInterface: 8000000}, roundValues(valuesByInterface(bitsFamily)))
+			assert.Nil(t, collector.newBitsRateFamily("err_in", rateFamily))
+		})
+	}
+}
+
+func roundValues(values map[string]float64) map[string]float64 {
+	for k, v := range values {
+		values[k] = float64(int64(v + 0.5))
+	}
+	return values
+}
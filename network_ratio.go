@@ -0,0 +1,56 @@
+package main
+
+import (
+	dto "github.com/prometheus/client_model/go"
+)
+
+// ratioFamilies lists the families derived from the increase of two counters since the previous run
+var ratioFamilies = []struct {
+	name        string
+	help        string
+	numerator   string
+	denominator string
+}{
+	{"err_in_ratio", "inbound errors per packet received", "err_in", "packets_recv"},
+	{"err_out_ratio", "outbound errors per packet sent", "err_out", "packets_sent"},
+	{"drop_in_ratio", "incoming packets dropped per packet received", "drop_in", "packets_recv"},
+	{"drop_out_ratio", "outbound packets dropped per packet sent", "drop_out", "packets_sent"},
+	{"avg_packet_size_recv", "average size in bytes of the packets received", "bytes_recv", "packets_recv"},
+	{"avg_packet_size_sent", "average size in bytes of the packets sent", "bytes_sent", "packets_sent"},
+}
+
+// newRatioFamilies returns the ratio families, e.g. err_in_ratio, computed from the counter deltas of each interface,
+// including the interface="all" and interface="other" sums. A ratio is skipped when either counter has no previous
+// value or when the denominator didn't increase.
+func (c *MetricCollector) newRatioFamilies(families []*dto.MetricFamily) []*dto.MetricFamily {
+	familyMap := map[string]*dto.MetricFamily{}
+	for _, family := range families {
+		familyMap[family.GetName()] = family
+	}
+
+	ratios := make([]*dto.MetricFamily, 0)
+	for _, ratio := range ratioFamilies {
+		denominators := map[string]float64{}
+		for _, m := range familyMap[ratio.denominator].GetMetric() {
+			if delta, ok := c.deltas[m]; ok && delta > 0 {
+				denominators[labelsKey(m)] = delta
+			}
+		}
+
+		family := newMetricFamily(ratio.name, ratio.help, dto.MetricType_GAUGE)
+		for _, m := range familyMap[ratio.numerator].GetMetric() {
+			delta, ok := c.deltas[m]
+			denominator, hasDenominator := denominators[labelsKey(m)]
+			if !ok || !hasDenominator {
+				continue
+			}
+			ifName, _ := getLabelValue(m, interfaceLabel)
+			newGaugeMetric(family, ifName, delta/denominator, m.GetTimestampMs())
+		}
+		if len(family.Metric) > 0 {
+			ratios = append(ratios, family)
+		}
+	}
+
+	return ratios
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func GetNetStatsRatioMock1(_ *selector) (NetStats, error) {
+	return NetStats{
+		"bytes_recv": map[string]float64{
+			"eno1": 100000,
+			"eno2": 5000,
+			"lo":   700,
+		}, "packets_recv": map[string]float64{
+			"eno1": 1000,
+			"eno2": 50,
+			"lo":   7,
+		}, "err_in": map[string]float64{
+			"eno1": 10,
+			"eno2": 0,
+			"lo":   0,
+		},
+	}, nil
+}
+
+func GetNetStatsRatioMock2(_ *selector) (NetStats, error) {
+	return NetStats{
+		"bytes_recv": map[string]float64{
+			"eno1": 250000,
+			"eno2": 5000,
+			"lo":   1700,
+		}, "packets_recv": map[string]float64{
+			"eno1": 2000,
+			"eno2": 50,
+			"lo":   17,
+		}, "err_in": map[string]float64{
+			"eno1": 60,
+			"eno2": 3,
+			"lo":   0,
+		},
+	}, nil
+}
+
+func TestMetricCollector_Ratios(t *testing.T) {
+	tmpFile := filepath.Join(t.TempDir(), uuid.New().String()+".json")
+	collector, err := NewCollector(CollectorOptions{Sum: true, StateFile: tmpFile, MaxRateIntervalSeconds: 60})
+	assert.NoError(t, err)
+	families, err := collector.Collect(GetNetStatsRatioMock1)
+	assert.NoError(t, err)
+	// no previous value, no ratio
+	assert.Nil(t, familiesByName(families)["err_in_ratio"])
+
+	families, err = collector.Collect(GetNetStatsRatioMock2)
+	assert.NoError(t, err)
+	familyMap := familiesByName(families)
+
+	// eno2 received no packet
+	assert.Equal(t, map[string]float64{"eno1": 0.05, "lo": 0, sumInterface: 53.0 / 1010}, valuesByInterface(familyMap["err_in_ratio"]))
+	assert.Equal(t, map[string]float64{"eno1": 150, "lo": 100, sumInterface: 151000.0 / 1010},
+		valuesByInterface(familyMap["avg_packet_size_recv"]))
+	assert.Equal(t, "average size in bytes of the packets received", familyMap["avg_packet_size_recv"].GetHelp())
+	assert.Nil(t, familyMap["drop_in_ratio"])
+	assert.Nil(t, familyMap["avg_packet_size_sent"])
+}
+
+func TestMetricCollector_RatiosFolded(t *testing.T) {
+	tmpFile := filepath.Join(t.TempDir(), uuid.New().String()+".json")
+	collector, err := NewCollector(CollectorOptions{StateFile: tmpFile, MaxRateIntervalSeconds: 60, MaxInterfaces: 1})
+	assert.NoError(t, err)
+	_, err = collector.Collect(GetNetStatsRatioMock1)
+	assert.NoError(t, err)
+	families, err := collector.Collect(GetNetStatsRatioMock2)
+	assert.NoError(t, err)
+
+	// the ratio of the folded interfaces is computed from their summed deltas
+	assert.Equal(t, map[string]float64{"eno1": 150, otherInterface: 100}, valuesByInterface(familiesByName(families)["avg_packet_size_recv"]))
+	assert.Equal(t, map[string]float64{"eno1": 0.05, otherInterface: 0.3}, valuesByInterface(familiesByName(families)["err_in_ratio"]))
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"strings"
+
+	dto "github.com/prometheus/client_model/go"
+)
+
+const (
+	sumoFamilyName = "host_net"
+
+	// sumoTotalSuffix is appended to the field of the cumulative counters, the Sumo Logic fields being per second
+	sumoTotalSuffix = "Total"
+)
+
+// sumoFieldNames maps the counters to the fields of the Sumo Logic Host Metrics source
+var sumoFieldNames = map[string]string{
+	"bytes_recv":   "Net_InBytes",
+	"bytes_sent":   "Net_OutBytes",
+	"packets_recv": "Net_InPackets",
+	"packets_sent": "Net_OutPackets",
+	"err_in":       "Net_InErrors",
+	"err_out":      "Net_OutErrors",
+	"drop_in":      "Net_InDropped",
+	"drop_out":     "Net_OutDropped",
+}
+
+// sumoField returns the host_net field of a counter or rate family. Rates are named after the Sumo Logic Host Metrics
+// fields, e.g. Net_InBytes for bytes_recv_rate, and the counters get the "Total" suffix, e.g. Net_InBytesTotal.
+// Families without Sumo Logic equivalent keep their name.
+func sumoField(name string) string {
+	if counterName := strings.TrimSuffix(name, rateFamilySuffix); counterName != name {
+		if field, ok := sumoFieldNames[counterName]; ok {
+			return field
+		}
+		return name
+	}
+	if field, ok := sumoFieldNames[name]; ok {
+		return field + sumoTotalSuffix
+	}
+	return name
+}
+
+// newSumoFamily returns the Sumo Logic compatible host_net family, with the Sumo Logic field names when enabled and
+// the counter names otherwise.
+func (c *MetricCollector) newSumoFamily(families []*dto.MetricFamily) *dto.MetricFamily {
+	if c.sumoFieldNames {
+		return c.newSumoFieldsFamily(families)
+	}
+
+	sumoFamily := newMetricFamily(sumoFamilyName, metricHelp[sumoFamilyName], dto.MetricType_COUNTER)
+	for _, family := range families {
+		if family.GetType() != dto.MetricType_COUNTER {
+			continue
+		}
+		for _, m := range family.Metric {
+			ifName, _ := getLabelValue(m, interfaceLabel)
+			if ifName == sumInterface {
+				continue
+			}
+			counter := newSumoCounterMetric(sumoFamily, family.GetName(), ifName, getMetricValue(m), m.GetTimestampMs())
+			if createdMS, ok := c.createdMS[m]; ok {
+				c.createdMS[counter] = createdMS
+			}
+		}
+	}
+
+	return sumoFamily
+}
+
+// newSumoFieldsFamily returns the host_net family with the Sumo Logic field names, with a field label per counter and
+// rate, including the interface="all" sums. The family is untyped since it mixes counters and rates, and rates are in
+// units per second whatever the rate unit.
+func (c *MetricCollector) newSumoFieldsFamily(families []*dto.MetricFamily) *dto.MetricFamily {
+	counterFamilies := map[string]struct{}{}
+	for _, family := range families {
+		if family.GetType() == dto.MetricType_COUNTER {
+			counterFamilies[family.GetName()] = struct{}{}
+		}
+	}
+
+	sumoFamily := newMetricFamily(sumoFamilyName, metricHelp[sumoFamilyName], dto.MetricType_UNTYPED)
+	for _, family := range families {
+		scale := float64(1)
+		if family.GetType() != dto.MetricType_COUNTER {
+			counterName := strings.TrimSuffix(family.GetName(), rateFamilySuffix)
+			if _, ok := counterFamilies[counterName]; !ok || counterName == family.GetName() {
+				continue
+			}
+			scale = c.rateScale(counterName)
+		}
+
+		field := sumoField(family.GetName())
+		for _, m := range family.Metric {
+			ifName, _ := getLabelValue(m, interfaceLabel)
+			newSumoMetric(sumoFamily, field, ifName, getMetricValue(m)/scale, m.GetTimestampMs())
+		}
+	}
+
+	return sumoFamily
+}
+
+func newSumoCounterMetric(family *dto.MetricFamily, fieldName string, ifName string, value float64, timestampMS int64) *dto.Metric {
+	counter := &dto.Metric{
+		Label: []*dto.LabelPair{
+			{Name: &interfaceLabel, Value: &ifName},
+			{Name: &fieldLabel, Value: &fieldName},
+		},
+		Counter: &dto.Counter{
+			Value: &value,
+		},
+		TimestampMs: &timestampMS,
+	}
+	family.Metric = append(family.Metric, counter)
+
+	return counter
+}
+
+func newSumoMetric(family *dto.MetricFamily, fieldName string, ifName string, value float64, timestampMS int64) *dto.Metric {
+	metric := &dto.Metric{
+		Label: []*dto.LabelPair{
+			{Name: &interfaceLabel, Value: &ifName},
+			{Name: &fieldLabel, Value: &fieldName},
+		},
+		Untyped: &dto.Untyped{
+			Value: &value,
+		},
+		TimestampMs: &timestampMS,
+	}
+	family.Metric = append(family.Metric, metric)
+
+	return metric
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetricCollector_SumoLogic(t *testing.T) {
+	tmpFile := filepath.Join(t.TempDir(), uuid.New().String()+".json")
+	collector, err := NewCollector(CollectorOptions{Sum: true, SumoLogic: true, StateFile: tmpFile, MaxRateIntervalSeconds: 60})
+	assert.NoError(t, err)
+	_, err = collector.Collect(GetNetStatsMock1)
+	assert.NoError(t, err)
+	time.Sleep(10 * time.Millisecond)
+	families, err := collector.Collect(GetNetStatsMock2)
+	assert.NoError(t, err)
+
+	// counters only, named after the counter, without the sums
+	family := familiesByName(families)[sumoFamilyName]
+	assert.Equal(t, dto.MetricType_COUNTER, family.GetType())
+	var keys []string
+	for _, m := range family.Metric {
+		keys = append(keys, sortedLabelsKey(m))
+		assert.Contains(t, collector.createdMS, m)
+	}
+	assert.Equal(t, []string{
+		"field=bytes_sent,interface=eno1,", "field=bytes_sent,interface=eno2,",
+		"field=err_in,interface=eno1,", "field=err_in,interface=eno2,",
+	}, keys)
+}
+
+func TestMetricCollector_SumoLogicFieldNames(t *testing.T) {
+	tmpFile := filepath.Join(t.TempDir(), uuid.New().String()+".json")
+	options := CollectorOptions{Sum: true, SumoLogicFieldNames: true, StateFile: tmpFile, MaxRateIntervalSeconds: 60}
+	collector, err := NewCollector(options)
+	assert.NoError(t, err)
+	families, err := collector.Collect(GetNetStatsMock1)
+	assert.NoError(t, err)
+	family := familiesByName(families)[sumoFamilyName]
+	assert.Equal(t, dto.MetricType_UNTYPED, family.GetType())
+	var keys []string
+	for _, m := range family.Metric {
+		keys = append(keys, sortedLabelsKey(m))
+	}
+	assert.Equal(t, []string{
+		"field=Net_InErrorsTotal,interface=eno1,", "field=Net_InErrorsTotal,interface=eno2,",
+		"field=Net_OutBytesTotal,interface=eno1,", "field=Net_OutBytesTotal,interface=eno2,",
+		"field=Net_InErrorsTotal,interface=all,", "field=Net_OutBytesTotal,interface=all,",
+	}, keys)
+
+	// the rates are added from the second run, per second
+	time.Sleep(10 * time.Millisecond)
+	collector, err = NewCollector(options)
+	assert.NoError(t, err)
+	families, err = collector.Collect(GetNetStatsMock2)
+	assert.NoError(t, err)
+	fields := map[string]int{}
+	for _, m := range familiesByName(families)[sumoFamilyName].Metric {
+		field, _ := getLabelValue(m, fieldLabel)
+		fields[field]++
+	}
+	assert.Equal(t, map[string]int{"Net_InErrorsTotal": 3, "Net_OutBytesTotal": 3, "Net_InErrors": 3, "Net_OutBytes": 3},
+		fields)
+}
+
+func TestSumoField(t *testing.T) {
+	assert.Equal(t, "Net_InBytes", sumoField("bytes_recv_rate"))
+	assert.Equal(t, "Net_InBytesTotal", sumoField("bytes_recv"))
+	assert.Equal(t, "Net_OutDroppedTotal", sumoField("drop_out"))
+	assert.Equal(t, "mtu", sumoField("mtu"))
+	assert.Equal(t, "bits_recv_rate", sumoField("bits_recv_rate"))
+}
@@ -37,7 +37,7 @@ func GetNetStatsMock2(_ *selector) (NetStats, error) {
 func TestMetricCollector_CollectWithSum(t *testing.T) {
 	tmpFile := filepath.Join(t.TempDir(), uuid.New().String()+".json")
 	// Sum, no rates
-	collector, err := NewCollector([]string{}, []string{}, true, true, tmpFile, 60)
+	collector, err := NewCollector(CollectorOptions{Sum: true, SumoLogic: true, StateFile: tmpFile, MaxRateIntervalSeconds: 60})
 	assert.NoError(t, err)
 	families, err := collector.Collect(GetNetStatsMock1)
 	assert.NoError(t, err)
@@ -60,15 +60,16 @@ func TestMetricCollector_CollectWithSum(t *testing.T) {
 	time.Sleep(10 * time.Millisecond)
 
 	// Second run there will be sum and rates
-	collector, err = NewCollector([]string{}, []string{}, true, true, tmpFile, 60)
+	collector, err = NewCollector(CollectorOptions{Sum: true, SumoLogic: true, StateFile: tmpFile, MaxRateIntervalSeconds: 60})
 	assert.NoError(t, err)
 	families, err = collector.Collect(GetNetStatsMock2)
 	assert.NoError(t, err)
 	assert.NotNil(t, families)
-	assert.Len(t, families, 5)
+	assert.Len(t, families, 6)
 	familyMap = familiesByName(families)
 	assert.Contains(t, familyMap, "bytes_sent")
 	assert.Contains(t, familyMap, "bytes_sent_rate")
+	assert.Contains(t, familyMap, "bits_sent_rate")
 	assert.Contains(t, familyMap, "err_in")
 	assert.Contains(t, familyMap, "err_in_rate")
 	for _, family := range families {
@@ -87,7 +88,7 @@ func TestMetricCollector_CollectNoSum(t *testing.T) {
 	tmpFile := filepath.Join(t.TempDir(), uuid.New().String()+".json")
 
 	// Sum, no rates
-	collector, err := NewCollector([]string{}, []string{}, false, true, tmpFile, 60)
+	collector, err := NewCollector(CollectorOptions{SumoLogic: true, StateFile: tmpFile, MaxRateIntervalSeconds: 60})
 	assert.NoError(t, err)
 	families, err := collector.Collect(GetNetStatsMock1)
 	assert.NoError(t, err)
@@ -110,15 +111,16 @@ func TestMetricCollector_CollectNoSum(t *testing.T) {
 	time.Sleep(10 * time.Millisecond)
 
 	// Second run there will be sum and rates
-	collector, err = NewCollector([]string{}, []string{}, false, true, tmpFile, 60)
+	collector, err = NewCollector(CollectorOptions{SumoLogic: true, StateFile: tmpFile, MaxRateIntervalSeconds: 60})
 	assert.NoError(t, err)
 	families, err = collector.Collect(GetNetStatsMock2)
 	assert.NoError(t, err)
 	assert.NotNil(t, families)
-	assert.Len(t, families, 5)
+	assert.Len(t, families, 6)
 	familyMap = familiesByName(families)
 	assert.Contains(t, familyMap, "bytes_sent")
 	assert.Contains(t, familyMap, "bytes_sent_rate")
+	assert.Contains(t, familyMap, "bits_sent_rate")
 	assert.Contains(t, familyMap, "err_in")
 	assert.Contains(t, familyMap, "err_in_rate")
 	for _, family := range families {
@@ -136,7 +138,7 @@ func TestCollect_NoFile(t *testing.T) {
 	tmpFile := filepath.Join(t.TempDir(), uuid.New().String()+".json")
 
 	// No rates since it's first time writing to file
-	collector, err := NewCollector([]string{}, []string{}, false, true, tmpFile, 60)
+	collector, err := NewCollector(CollectorOptions{SumoLogic: true, StateFile: tmpFile, MaxRateIntervalSeconds: 60})
 	assert.NoError(t, err)
 	families, err := collector.Collect(GetNetStatsMock1)
 	assert.NoError(t, err)
@@ -156,7 +158,7 @@ func TestCollect_NoFile(t *testing.T) {
 	}
 
 	// Second run with no file there will be no rates
-	collector, err = NewCollector([]string{}, []string{}, false, true, "", 60)
+	collector, err = NewCollector(CollectorOptions{SumoLogic: true, MaxRateIntervalSeconds: 60})
 	assert.NoError(t, err)
 	families, err = collector.Collect(GetNetStatsMock2)
 	assert.NoError(t, err)
@@ -173,7 +175,7 @@ func TestCollect_MaxRateInterval(t *testing.T) {
 	tmpFile := filepath.Join(t.TempDir(), uuid.New().String()+".json")
 
 	// First run to generate a state file
-	collector, err := NewCollector([]string{}, []string{}, false, true, tmpFile, 3)
+	collector, err := NewCollector(CollectorOptions{SumoLogic: true, StateFile: tmpFile, MaxRateIntervalSeconds: 3})
 	assert.NoError(t, err)
 	families, err := collector.Collect(GetNetStatsMock1)
 	assert.NoError(t, err)
@@ -194,21 +196,22 @@ func TestCollect_MaxRateInterval(t *testing.T) {
 
 	// Second run with a small delay, should produce rate metrics
 	time.Sleep(time.Second * 1)
-	collector, err = NewCollector([]string{}, []string{}, false, true, tmpFile, 3)
+	collector, err = NewCollector(CollectorOptions{SumoLogic: true, StateFile: tmpFile, MaxRateIntervalSeconds: 3})
 	assert.NoError(t, err)
 	families, err = collector.Collect(GetNetStatsMock2)
 	assert.NoError(t, err)
 	assert.NotNil(t, families)
-	assert.Len(t, families, 5)
+	assert.Len(t, families, 6)
 	familyMap = familiesByName(families)
 	assert.Contains(t, familyMap, "bytes_sent")
 	assert.Contains(t, familyMap, "bytes_sent_rate")
+	assert.Contains(t, familyMap, "bits_sent_rate")
 	assert.Contains(t, familyMap, "err_in")
 	assert.Contains(t, familyMap, "err_in_rate")
 
 	// Third run with a long delay, no rates should be produced
 	time.Sleep(time.Second * 3)
-	collector, err = NewCollector([]string{}, []string{}, false, true, tmpFile, 1)
+	collector, err = NewCollector(CollectorOptions{SumoLogic: true, StateFile: tmpFile, MaxRateIntervalSeconds: 1})
 	assert.NoError(t, err)
 	families, err = collector.Collect(GetNetStatsMock2)
 	assert.NoError(t, err)
@@ -222,15 +225,16 @@ func TestCollect_MaxRateInterval(t *testing.T) {
 
 	// Fourth run with a long delay and 0 interval, should be produced
 	time.Sleep(time.Second * 3)
-	collector, err = NewCollector([]string{}, []string{}, false, true, tmpFile, 0)
+	collector, err = NewCollector(CollectorOptions{SumoLogic: true, StateFile: tmpFile, MaxRateIntervalSeconds: 0})
 	assert.NoError(t, err)
 	families, err = collector.Collect(GetNetStatsMock2)
 	assert.NoError(t, err)
 	assert.NotNil(t, families)
-	assert.Len(t, families, 5)
+	assert.Len(t, families, 6)
 	familyMap = familiesByName(families)
 	assert.Contains(t, familyMap, "bytes_sent")
 	assert.Contains(t, familyMap, "bytes_sent_rate")
+	assert.Contains(t, familyMap, "bits_sent_rate")
 	assert.Contains(t, familyMap, "err_in")
 	assert.Contains(t, familyMap, "err_in_rate")
 }
@@ -254,3 +258,176 @@ func hasSumMetric(family *dto.MetricFamily) bool {
 	}
 	return false
 }
+
+func valuesByInterface(family *dto.MetricFamily) map[string]float64 {
+	values := map[string]float64{}
+	for _, metric := range family.GetMetric() {
+		ifName, _ := getLabelValue(metric, interfaceLabel)
+		values[ifName] = getMetricValue(metric)
+	}
+
+	return values
+}
+
+func TestMetricCollector_CreatedTimestamps(t *testing.T) {
+	tmpFile := filepath.Join(t.TempDir(), uuid.New().String()+".json")
+
+	collector, err := NewCollector(CollectorOptions{Sum: true, SumoLogic: true, StateFile: tmpFile, MaxRateIntervalSeconds: 60})
+	assert.NoError(t, err)
+	families, err := collector.Collect(GetNetStatsMock1)
+	assert.NoError(t, err)
+	firstTimestampMS := familiesByName(families)["bytes_sent"].Metric[0].GetTimestampMs()
+
+	// small sleep to ensure second call to Collect has different timestamp
+	time.Sleep(10 * time.Millisecond)
+
+	collector, err = NewCollector(CollectorOptions{Sum: true, SumoLogic: true, StateFile: tmpFile, MaxRateIntervalSeconds: 60})
+	assert.NoError(t, err)
+	families, err = collector.Collect(GetNetStatsMock2)
+	assert.NoError(t, err)
+	for _, family := range families {
+		for _, metric := range family.Metric {
+			createdMS, ok := collector.createdMS[metric]
+			if family.GetType() == dto.MetricType_COUNTER && metricInterface(metric) != sumInterface {
+				assert.True(t, ok)
+				assert.Equal(t, firstTimestampMS, createdMS)
+			} else {
+				assert.False(t, ok)
+			}
+		}
+	}
+
+	// no state file, no creation timestamp
+	collector, err = NewCollector(CollectorOptions{MaxRateIntervalSeconds: 60})
+	assert.NoError(t, err)
+	_, err = collector.Collect(GetNetStatsMock1)
+	assert.NoError(t, err)
+	assert.Empty(t, collector.createdMS)
+}
+
+func TestMetricCollector_SortedOutput(t *testing.T) {
+	collector, err := NewCollector(CollectorOptions{Sum: true, SumoLogic: true, MaxRateIntervalSeconds: 60})
+	assert.NoError(t, err)
+
+	for i := 0; i < 10; i++ {
+		families, err := collector.Collect(GetNetStatsMock1)
+		assert.NoError(t, err)
+
+		var names []string
+		for _, family := range families {
+			names = append(names, family.GetName())
+		}
+		assert.Equal(t, []string{"bytes_sent", "err_in", "host_net"}, names)
+
+		for _, family := range families[:2] {
+			var interfaces []string
+			for _, m := range family.Metric {
+				interfaces = append(interfaces, metricInterface(m))
+			}
+			assert.Equal(t, []string{"eno1", "eno2", sumInterface}, interfaces)
+		}
+		var keys []string
+		for _, m := range families[2].Metric {
+			keys = append(keys, sortedLabelsKey(m))
+		}
+		assert.Equal(t, []string{
+			"field=bytes_sent,interface=eno1,", "field=bytes_sent,interface=eno2,",
+			"field=err_in,interface=eno1,", "field=err_in,interface=eno2,",
+		}, keys)
+	}
+}
+
+func TestMetricCollector_Deltas(t *testing.T) {
+	tmpFile := filepath.Join(t.TempDir(), uuid.New().String()+".json")
+	collector, err := NewCollector(CollectorOptions{Sum: true, StateFile: tmpFile, MaxRateIntervalSeconds: 60,
+		DeltaMetrics: true})
+	assert.NoError(t, err)
+	_, err = collector.Collect(GetNetStatsMock2)
+	assert.NoError(t, err)
+	assert.Empty(t, collector.deltas)
+
+	// counters lower than their previous value were reset
+	time.Sleep(10 * time.Millisecond)
+	families, err := collector.Collect(GetNetStatsMock1)
+	assert.NoError(t, err)
+	deltas := map[string]float64{}
+	for _, m := range familiesByName(families)["err_in"].Metric {
+		deltas[metricInterface(m)] = collector.deltas[m]
+	}
+	assert.Equal(t, map[string]float64{"eno1": 2, "eno2": 4, "all": 6}, deltas)
+	assert.Equal(t, deltas, valuesByInterface(familiesByName(families)["err_in_delta"]))
+	assert.Equal(t, "inbound errors since the previous sample", familiesByName(families)["err_in_delta"].GetHelp())
+	intervals := valuesByInterface(familiesByName(families)[deltaIntervalFamily])
+	assert.Len(t, intervals, 2)
+	assert.GreaterOrEqual(t, intervals["eno1"], 0.01)
+	assert.GreaterOrEqual(t, intervals["eno2"], 0.01)
+
+	// the rates are computed from the deltas, so a reset doesn't make them negative
+	rates := valuesByInterface(familiesByName(families)["err_in_rate"])
+	assert.Len(t, rates, 3)
+	assert.InDelta(t, rates["eno2"], 2*rates["eno1"], 1e-9)
+	for _, rate := range rates {
+		assert.Greater(t, rate, float64(0))
+	}
+
+	// the deltas are still computed, but not emitted by default
+	collector, err = NewCollector(CollectorOptions{Sum: true, StateFile: tmpFile, MaxRateIntervalSeconds: 60})
+	assert.NoError(t, err)
+	families, err = collector.Collect(GetNetStatsMock2)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, collector.deltas)
+	assert.NotContains(t, familiesByName(families), "err_in_delta")
+	assert.NotContains(t, familiesByName(families), deltaIntervalFamily)
+}
+
+func TestCounterDelta(t *testing.T) {
+	assert.Equal(t, float64(5), counterDelta(10, 15))
+	assert.Equal(t, float64(0), counterDelta(10, 10))
+	assert.Equal(t, float64(3), counterDelta(10, 3))
+}
+
+func TestMetricCollector_Lifetime(t *testing.T) {
+	tmpFile := filepath.Join(t.TempDir(), uuid.New().String()+".json")
+	collect := func(bootID string, netStatsGetter func(*selector) (NetStats, error)) map[string]*dto.MetricFamily {
+		collector, err := NewCollector(CollectorOptions{
+			Sum:                    true,
+			SumoLogic:              true,
+			StateFile:              tmpFile,
+			MaxRateIntervalSeconds: 60,
+			DeltaMetrics:           true,
+			LifetimeCounters:       true,
+			BootID:                 bootID,
+		})
+		assert.NoError(t, err)
+		families, err := collector.Collect(netStatsGetter)
+		assert.NoError(t, err)
+		return familiesByName(families)
+	}
+
+	familyMap := collect("boot1", GetNetStatsMock1)
+	assert.Equal(t, map[string]float64{"eno1": 2, "eno2": 4, "all": 6}, valuesByInterface(familyMap["err_in_lifetime"]))
+	assert.Equal(t, dto.MetricType_COUNTER, familyMap["err_in_lifetime"].GetType())
+	for _, m := range familyMap[sumoFamilyName].Metric {
+		field, _ := getLabelValue(m, fieldLabel)
+		assert.NotContains(t, field, lifetimeFamilySuffix)
+	}
+
+	familyMap = collect("boot1", GetNetStatsMock2)
+	assert.Equal(t, map[string]float64{"eno1": 8, "eno2": 12, "all": 20}, valuesByInterface(familyMap["err_in_lifetime"]))
+
+	// counters lower than their previous value were reset
+	familyMap = collect("boot1", GetNetStatsMock1)
+	assert.Equal(t, map[string]float64{"eno1": 10, "eno2": 16, "all": 26}, valuesByInterface(familyMap["err_in_lifetime"]))
+
+	// after a reboot the counters were reset even though they increased
+	familyMap = collect("boot2", GetNetStatsMock2)
+	assert.Equal(t, map[string]float64{"eno1": 18, "eno2": 28, "all": 46}, valuesByInterface(familyMap["err_in_lifetime"]))
+	assert.Equal(t, map[string]float64{"eno1": 8, "eno2": 12, "all": 20}, valuesByInterface(familyMap["err_in_delta"]))
+
+	// not emitted by default
+	collector, err := NewCollector(CollectorOptions{Sum: true, SumoLogic: true, StateFile: tmpFile, MaxRateIntervalSeconds: 60})
+	assert.NoError(t, err)
+	families, err := collector.Collect(GetNetStatsMock2)
+	assert.NoError(t, err)
+	assert.NotContains(t, familiesByName(families), "err_in_lifetime")
+}
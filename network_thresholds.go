@@ -0,0 +1,143 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/sensu/sensu-plugin-sdk/sensu"
+)
+
+var statusNames = map[int]string{
+	sensu.CheckStateOK:       "OK",
+	sensu.CheckStateWarning:  "WARNING",
+	sensu.CheckStateCritical: "CRITICAL",
+	sensu.CheckStateUnknown:  "UNKNOWN",
+}
+
+// Alert is a condition detected by the collector that raises the check status.
+type Alert struct {
+	Status    int
+	Rule      string
+	Interface string
+	Message   string
+}
+
+// threshold holds the warning and critical values of a metric family, nil when not configured.
+type threshold struct {
+	warning  *float64
+	critical *float64
+}
+
+// parseThresholds parses the metric=value warning and critical thresholds into thresholds by metric family name.
+func parseThresholds(warning, critical map[string]string) (map[string]*threshold, error) {
+	thresholds := map[string]*threshold{}
+	parse := func(values map[string]string, kind string, set func(*threshold, *float64)) error {
+		for name, value := range values {
+			v, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+			if err != nil {
+				return fmt.Errorf("invalid %s threshold %q for %s", kind, value, name)
+			}
+			name = strings.TrimSpace(name)
+			if thresholds[name] == nil {
+				thresholds[name] = &threshold{}
+			}
+			set(thresholds[name], &v)
+		}
+		return nil
+	}
+
+	if err := parse(warning, "warning", func(t *threshold, v *float64) { t.warning = v }); err != nil {
+		return nil, err
+	}
+	if err := parse(critical, "critical", func(t *threshold, v *float64) { t.critical = v }); err != nil {
+		return nil, err
+	}
+
+	return thresholds, nil
+}
+
+// status returns the check status of the value, a threshold is breached when the value reaches it.
+func (t *threshold) status(value float64) int {
+	if t.critical != nil && value >= *t.critical {
+		return sensu.CheckStateCritical
+	}
+	if t.warning != nil && value >= *t.warning {
+		return sensu.CheckStateWarning
+	}
+	return sensu.CheckStateOK
+}
+
+// evaluateThresholds raises an alert for each metric breaching the threshold of its family.
+func (c *MetricCollector) evaluateThresholds(families []*dto.MetricFamily) {
+	for _, family := range families {
+		t := c.thresholds[family.GetName()]
+		if t == nil {
+			continue
+		}
+		for _, m := range family.Metric {
+			value := getMetricValue(m)
+			status := t.status(value)
+			if status == sensu.CheckStateOK {
+				continue
+			}
+			ifName, _ := getLabelValue(m, interfaceLabel)
+			limit := t.warning
+			if status == sensu.CheckStateCritical {
+				limit = t.critical
+			}
+			c.alerts = append(c.alerts, Alert{
+				Status:    status,
+				Rule:      "threshold:" + family.GetName(),
+				Interface: ifName,
+				Message:   fmt.Sprintf("%s on %s is %s (>= %s)", family.GetName(), ifName, formatValue(value), formatValue(*limit)),
+			})
+		}
+	}
+}
+
+// mapOutputThresholds records the thresholds by emitted family name, once the families have been renamed.
+func (c *MetricCollector) mapOutputThresholds(families []*dto.MetricFamily, nativeNames map[*dto.MetricFamily]string) {
+	c.outputThresholds = map[string]*threshold{}
+	for _, family := range families {
+		if t := c.thresholds[nativeNames[family]]; t != nil {
+			c.outputThresholds[family.GetName()] = t
+		}
+	}
+}
+
+// Alerting returns whether alert rules are configured: thresholds, traffic quotas, anomaly detection or idle threshold.
+func (c *MetricCollector) Alerting() bool {
+	return len(c.thresholds) > 0 || len(c.trafficQuotas) > 0 || c.anomalyZScore > 0 || c.idleThreshold > 0
+}
+
+// Alerts returns the alerts raised by the last Collect, sorted by decreasing status, rule and interface.
+func (c *MetricCollector) Alerts() []Alert {
+	sort.SliceStable(c.alerts, func(i, j int) bool {
+		if c.alerts[i].Status != c.alerts[j].Status {
+			return c.alerts[i].Status > c.alerts[j].Status
+		}
+		if c.alerts[i].Rule != c.alerts[j].Rule {
+			return c.alerts[i].Rule < c.alerts[j].Rule
+		}
+		return c.alerts[i].Interface < c.alerts[j].Interface
+	})
+	return c.alerts
+}
+
+// Status returns the check status resulting from the alerts raised by the last Collect.
+func (c *MetricCollector) Status() int {
+	status := sensu.CheckStateOK
+	for _, alert := range c.alerts {
+		if alert.Status > status {
+			status = alert.Status
+		}
+	}
+	return status
+}
+
+func formatValue(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/sensu/sensu-plugin-sdk/sensu"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseThresholds(t *testing.T) {
+	thresholds, err := parseThresholds(map[string]string{"err_in": "5", "bytes_sent": "1e7"}, map[string]string{"err_in": " 10 "})
+	assert.NoError(t, err)
+	assert.Len(t, thresholds, 2)
+	assert.Equal(t, float64(5), *thresholds["err_in"].warning)
+	assert.Equal(t, float64(10), *thresholds["err_in"].critical)
+	assert.Equal(t, float64(1e7), *thresholds["bytes_sent"].warning)
+	assert.Nil(t, thresholds["bytes_sent"].critical)
+
+	_, err = parseThresholds(map[string]string{"err_in": "five"}, map[string]string{})
+	assert.Error(t, err)
+	_, err = parseThresholds(map[string]string{}, map[string]string{"err_in": ""})
+	assert.Error(t, err)
+}
+
+func TestThreshold_Status(t *testing.T) {
+	thresholds, _ := parseThresholds(map[string]string{"err_in": "5"}, map[string]string{"err_in": "10"})
+	assert.Equal(t, sensu.CheckStateOK, thresholds["err_in"].status(4.9))
+	assert.Equal(t, sensu.CheckStateWarning, thresholds["err_in"].status(5))
+	assert.Equal(t, sensu.CheckStateCritical, thresholds["err_in"].status(10))
+
+	thresholds, _ = parseThresholds(map[string]string{}, map[string]string{"err_in": "10"})
+	assert.Equal(t, sensu.CheckStateOK, thresholds["err_in"].status(9))
+	assert.Equal(t, sensu.CheckStateCritical, thresholds["err_in"].status(11))
+}
+
+func TestMetricCollector_Thresholds(t *testing.T) {
+	collector, err := NewCollector(CollectorOptions{
+		Sum:                    true,
+		MaxRateIntervalSeconds: 60,
+		Naming:                 namingNodeExporter,
+		Warning:                map[string]string{"err_in": "3"},
+		Critical:               map[string]string{"err_in": "6"},
+	})
+	assert.NoError(t, err)
+	_, err = collector.Collect(GetNetStatsMock1)
+	assert.NoError(t, err)
+	assert.Equal(t, sensu.CheckStateCritical, collector.Status())
+
+	alerts := collector.Alerts()
+	assert.Len(t, alerts, 2)
+	assert.Equal(t, Alert{
+		Status:    sensu.CheckStateCritical,
+		Rule:      "threshold:err_in",
+		Interface: "all",
+		Message:   "err_in on all is 6 (>= 6)",
+	}, alerts[0])
+	assert.Equal(t, Alert{
+		Status:    sensu.CheckStateWarning,
+		Rule:      "threshold:err_in",
+		Interface: "eno2",
+		Message:   "err_in on eno2 is 4 (>= 3)",
+	}, alerts[1])
+
+	// thresholds are mapped to the emitted family names
+	assert.Contains(t, collector.outputThresholds, "node_network_receive_errs_total")
+
+	// no alert raised
+	collector, err = NewCollector(CollectorOptions{Sum: true, MaxRateIntervalSeconds: 60, Warning: map[string]string{"err_in": "100"}})
+	assert.NoError(t, err)
+	_, err = collector.Collect(GetNetStatsMock1)
+	assert.NoError(t, err)
+	assert.Equal(t, sensu.CheckStateOK, collector.Status())
+	assert.Empty(t, collector.Alerts())
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/sensu/network-interface-checks/metric"
+	"github.com/sensu/sensu-plugin-sdk/sensu"
+)
+
+const (
+	trafficTodayFamily = "traffic_today_bytes"
+	trafficTodayHelp   = "bytes received and sent since the beginning of the day"
+	trafficMonthFamily = "traffic_month_bytes"
+	trafficMonthHelp   = "bytes received and sent since the beginning of the month"
+
+	quotaPeriodDay   = "day"
+	quotaPeriodMonth = "month"
+)
+
+// byteUnits holds the factor of the units accepted in traffic quotas, SI units being powers of 1000 and IEC units
+// powers of 1024.
+var byteUnits = map[string]float64{
+	"B":   1,
+	"KB":  1e3,
+	"MB":  1e6,
+	"GB":  1e9,
+	"TB":  1e12,
+	"PB":  1e15,
+	"KIB": 1 << 10,
+	"MIB": 1 << 20,
+	"GIB": 1 << 30,
+	"TIB": 1 << 40,
+	"PIB": 1 << 50,
+}
+
+// trafficQuota is the maximum number of bytes an interface may receive and send during a day or a month.
+type trafficQuota struct {
+	ifName string
+	period string
+	bytes  float64
+	text   string
+}
+
+// parseTrafficQuotas parses the quotas in interface=amount/period format, e.g. eth0=10TB/month.
+func parseTrafficQuotas(quotas []string) ([]trafficQuota, error) {
+	parsed := make([]trafficQuota, 0, len(quotas))
+	for _, quota := range quotas {
+		parts := strings.SplitN(strings.TrimSpace(quota), "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid traffic quota %q, expected <interface>=<amount>/<day|month>", quota)
+		}
+		ifName, value := parts[0], strings.SplitN(parts[1], "/", 2)
+		if ifName == "" || len(value) != 2 || (value[1] != quotaPeriodDay && value[1] != quotaPeriodMonth) {
+			return nil, fmt.Errorf("invalid traffic quota %q, expected <interface>=<amount>/<day|month>", quota)
+		}
+		amount, period := value[0], value[1]
+		bytes, err := parseBytes(amount)
+		if err != nil {
+			return nil, fmt.Errorf("invalid traffic quota %q: %v", quota, err)
+		}
+		parsed = append(parsed, trafficQuota{ifName: ifName, period: period, bytes: bytes, text: amount})
+	}
+	return parsed, nil
+}
+
+// parseBytes parses an amount of bytes with an optional unit, e.g. 10TB or 500GiB.
+func parseBytes(amount string) (float64, error) {
+	number := strings.TrimRight(amount, "BbIiKkMmGgTtPp")
+	unit := strings.ToUpper(strings.TrimSpace(amount[len(number):]))
+	if unit == "" {
+		unit = "B"
+	}
+	factor, ok := byteUnits[unit]
+	if !ok {
+		return 0, fmt.Errorf("unknown unit %q", amount[len(number):])
+	}
+	value, err := strconv.ParseFloat(strings.TrimSpace(number), 64)
+	if err != nil || value <= 0 {
+		return 0, fmt.Errorf("invalid amount %q", number)
+	}
+	return value * factor, nil
+}
+
+// newTrafficFamilies adds the bytes received and sent by each interface since the previous run to its traffic
+// buckets, and returns the traffic of the current day and month. The traffic quotas are evaluated against them.
+func (c *MetricCollector) newTrafficFamilies(families []*dto.MetricFamily, metricState *metric.CounterMetricState,
+	nowMS int64) []*dto.MetricFamily {
+	bytes := map[string]float64{}
+	for _, family := range families {
+		if family.GetName() != bytesRecvFamily && family.GetName() != bytesSentFamily {
+			continue
+		}
+		for _, m := range family.Metric {
+			if ifName, _ := getLabelValue(m, interfaceLabel); ifName != sumInterface {
+				bytes[ifName] += c.deltas[m]
+			}
+		}
+	}
+	if len(bytes) == 0 {
+		return nil
+	}
+
+	now := time.UnixMilli(nowMS).In(c.trafficLocation)
+	today := newMetricFamily(trafficTodayFamily, trafficTodayHelp, dto.MetricType_GAUGE)
+	month := newMetricFamily(trafficMonthFamily, trafficMonthHelp, dto.MetricType_GAUGE)
+	usage := map[string]map[string]float64{quotaPeriodDay: {}, quotaPeriodMonth: {}}
+	for ifName, ifBytes := range bytes {
+		traffic := metricState.AddTraffic(ifName, ifBytes, now)
+		usage[quotaPeriodDay][ifName] = traffic.Day.Bytes
+		usage[quotaPeriodMonth][ifName] = traffic.Month.Bytes
+		usage[quotaPeriodDay][sumInterface] += traffic.Day.Bytes
+		usage[quotaPeriodMonth][sumInterface] += traffic.Month.Bytes
+		newGaugeMetric(today, ifName, traffic.Day.Bytes, nowMS)
+		newGaugeMetric(month, ifName, traffic.Month.Bytes, nowMS)
+	}
+	if c.sum {
+		newGaugeMetric(today, sumInterface, usage[quotaPeriodDay][sumInterface], nowMS)
+		newGaugeMetric(month, sumInterface, usage[quotaPeriodMonth][sumInterface], nowMS)
+	}
+
+	c.evaluateQuotas(usage)
+
+	return []*dto.MetricFamily{today, month}
+}
+
+// evaluateQuotas raises an alert for each interface whose traffic reached the warning or critical percentage of its
+// quota.
+func (c *MetricCollector) evaluateQuotas(usage map[string]map[string]float64) {
+	t := &threshold{warning: &c.quotaWarning, critical: &c.quotaCritical}
+	for _, quota := range c.trafficQuotas {
+		used, ok := usage[quota.period][quota.ifName]
+		if !ok {
+			continue
+		}
+		percent := used / quota.bytes * 100
+		status := t.status(percent)
+		if status == sensu.CheckStateOK {
+			continue
+		}
+		c.alerts = append(c.alerts, Alert{
+			Status:    status,
+			Rule:      "quota:" + quota.period,
+			Interface: quota.ifName,
+			Message: fmt.Sprintf("%s traffic on %s is %s bytes, %s%% of the %s quota", quota.period, quota.ifName,
+				formatValue(used), strconv.FormatFloat(percent, 'f', 1, 64), quota.text),
+		})
+	}
+}
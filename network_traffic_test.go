@@ -0,0 +1,89 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/sensu/sensu-plugin-sdk/sensu"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTrafficQuotas(t *testing.T) {
+	quotas, err := parseTrafficQuotas([]string{"eth0=10TB/month", " all=500GiB/day", "eth1=1000/day"})
+	assert.NoError(t, err)
+	assert.Equal(t, []trafficQuota{
+		{ifName: "eth0", period: quotaPeriodMonth, bytes: 10e12, text: "10TB"},
+		{ifName: "all", period: quotaPeriodDay, bytes: 500 * (1 << 30), text: "500GiB"},
+		{ifName: "eth1", period: quotaPeriodDay, bytes: 1000, text: "1000"},
+	}, quotas)
+
+	for _, quota := range []string{"eth0", "eth0=10TB", "=10TB/month", "eth0=10TB/week", "eth0=10XB/month", "eth0=TB/month",
+		"eth0=-1TB/month"} {
+		_, err := parseTrafficQuotas([]string{quota})
+		assert.Error(t, err, quota)
+	}
+}
+
+func TestParseBytes(t *testing.T) {
+	testCases := map[string]float64{
+		"1":      1,
+		"1B":     1,
+		"1.5kb":  1500,
+		"2MB":    2e6,
+		"3 GB":   3e9,
+		"1TiB":   1 << 40,
+		"0.5PiB": 1 << 49,
+	}
+	for amount, expected := range testCases {
+		value, err := parseBytes(amount)
+		assert.NoError(t, err, amount)
+		assert.Equal(t, expected, value, amount)
+	}
+}
+
+func TestMetricCollector_Traffic(t *testing.T) {
+	tmpFile := filepath.Join(t.TempDir(), uuid.New().String()+".json")
+	newTrafficCollector := func() *MetricCollector {
+		collector, err := NewCollector(CollectorOptions{
+			Sum:                    true,
+			StateFile:              tmpFile,
+			MaxRateIntervalSeconds: 60,
+			TrafficTimezone:        "UTC",
+			TrafficQuotas:          []string{"eno1=20MB/day", "eno2=12MB/month", "all=15MB/day", "eno3=1B/day"},
+			TrafficQuotaWarning:    80,
+			TrafficQuotaCritical:   100,
+		})
+		assert.NoError(t, err)
+		return collector
+	}
+
+	collector := newTrafficCollector()
+	families, err := collector.Collect(GetNetStatsMock1)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]float64{"eno1": 0, "eno2": 0, "all": 0}, valuesByInterface(familiesByName(families)[trafficTodayFamily]))
+	assert.Empty(t, collector.Alerts())
+
+	collector = newTrafficCollector()
+	families, err = collector.Collect(GetNetStatsMock2)
+	assert.NoError(t, err)
+	familyMap := familiesByName(families)
+	assert.Equal(t, map[string]float64{"eno1": 1e7, "eno2": 1e7, "all": 2e7}, valuesByInterface(familyMap[trafficTodayFamily]))
+	assert.Equal(t, map[string]float64{"eno1": 1e7, "eno2": 1e7, "all": 2e7}, valuesByInterface(familyMap[trafficMonthFamily]))
+	assert.Equal(t, trafficMonthHelp, familyMap[trafficMonthFamily].GetHelp())
+
+	assert.Equal(t, sensu.CheckStateCritical, collector.Status())
+	assert.Equal(t, []Alert{
+		{Status: sensu.CheckStateCritical, Rule: "quota:day", Interface: "all",
+			Message: "day traffic on all is 20000000 bytes, 133.3% of the 15MB quota"},
+		{Status: sensu.CheckStateWarning, Rule: "quota:month", Interface: "eno2",
+			Message: "month traffic on eno2 is 10000000 bytes, 83.3% of the 12MB quota"},
+	}, collector.Alerts())
+
+	// not emitted without accounting
+	collector, err = NewCollector(CollectorOptions{Sum: true, StateFile: tmpFile, MaxRateIntervalSeconds: 60})
+	assert.NoError(t, err)
+	families, err = collector.Collect(GetNetStatsMock2)
+	assert.NoError(t, err)
+	assert.NotContains(t, familiesByName(families), trafficTodayFamily)
+}
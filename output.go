@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	v2 "github.com/sensu/sensu-go/api/core/v2"
+)
+
+const (
+	outputFormatPrometheus  = "prometheus"
+	outputFormatNagios      = "nagios-perfdata"
+	outputFormatInfluxDB    = "influxdb_line"
+	outputFormatGraphite    = "graphite_plaintext"
+	outputFormatOpenTSDB    = "opentsdb_line"
+	outputFormatJSON        = "json"
+	outputFormatOpenMetrics = "openmetrics"
+	outputFormatCarbon2     = "carbon2"
+)
+
+var outputFormats = []string{outputFormatPrometheus, outputFormatOpenMetrics, outputFormatNagios, outputFormatInfluxDB,
+	outputFormatGraphite, outputFormatOpenTSDB, outputFormatCarbon2, outputFormatJSON}
+
+func validOutputFormat(format string) bool {
+	for _, f := range outputFormats {
+		if format == f {
+			return true
+		}
+	}
+	return false
+}
+
+// writeMetrics writes the families collected by the collector in the output format, name being the check name of the
+// Nagios output and graphiteTemplate the metric path template of the Graphite output. The alerts are only written in
+// the Prometheus output when alert rules are configured.
+func writeMetrics(w io.Writer, format, name, graphiteTemplate string, families []*dto.MetricFamily,
+	collector *MetricCollector, event *v2.Event) error {
+	switch format {
+	case outputFormatOpenMetrics:
+		return encodeOpenMetrics(w, families, collector.createdMS)
+	case outputFormatNagios:
+		return encodeNagiosPerfdata(w, name, collector.Status(), collector.Alerts(), families, collector.outputThresholds)
+	case outputFormatInfluxDB:
+		return encodeInfluxDBLine(w, families)
+	case outputFormatGraphite:
+		return encodeGraphitePlaintext(w, families, graphiteTemplate, hostName(event))
+	case outputFormatOpenTSDB:
+		return encodeOpenTSDBLine(w, families, hostName(event))
+	case outputFormatCarbon2:
+		return encodeCarbon2(w, families, hostName(event))
+	case outputFormatJSON:
+		return encodeJSON(w, families)
+	default:
+		alerts := []Alert{}
+		if collector.Alerting() {
+			alerts = collector.Alerts()
+		}
+		return encodePrometheusText(w, alerts, families)
+	}
+}
+
+// encodePrometheusText writes the families in the Prometheus text exposition format. Alerts are written first as
+// comments, which are ignored by Prometheus parsers.
+func encodePrometheusText(w io.Writer, alerts []Alert, families []*dto.MetricFamily) error {
+	for _, alert := range alerts {
+		if _, err := fmt.Fprintf(w, "# %s: %s\n", statusNames[alert.Status], alert.Message); err != nil {
+			return err
+		}
+	}
+
+	encoder := expfmt.NewEncoder(w, expfmt.FmtText)
+	for _, family := range families {
+		if err := encoder.Encode(family); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
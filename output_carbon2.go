@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strings"
+
+	dto "github.com/prometheus/client_model/go"
+)
+
+var carbon2TagReplacer = strings.NewReplacer(" ", "_", "=", "_", "\t", "_", "\n", "_")
+
+// encodeCarbon2 writes the families in the Carbon 2.0 format used by Sumo Logic: "intrinsic_tags  meta_tags value epoch".
+// The metric and interface labels are written as intrinsic tags, the metric being the field of the host_net family,
+// e.g. metric=Net_InBytes, or the family name otherwise. The host and the other labels are written as meta tags.
+// Spaces and "=" in tags are replaced with "_".
+func encodeCarbon2(w io.Writer, families []*dto.MetricFamily, host string) error {
+	for _, family := range families {
+		for _, m := range family.Metric {
+			value := getMetricValue(m)
+			if math.IsNaN(value) || math.IsInf(value, 0) {
+				continue
+			}
+
+			metric := family.GetName()
+			if field, ok := getLabelValue(m, fieldLabel); ok && metric == sumoFamilyName {
+				metric = field
+			}
+			intrinsicTags := []string{carbon2Tag("metric", metric)}
+			if ifName, ok := getLabelValue(m, interfaceLabel); ok {
+				intrinsicTags = append(intrinsicTags, carbon2Tag(interfaceLabel, ifName))
+			}
+
+			metaTags := make([]string, 0, len(m.Label)+1)
+			if _, ok := getLabelValue(m, "host"); !ok {
+				metaTags = append(metaTags, carbon2Tag("host", host))
+			}
+			for _, label := range sortedLabels(m) {
+				if label.GetName() == interfaceLabel || (label.GetName() == fieldLabel && family.GetName() == sumoFamilyName) {
+					continue
+				}
+				metaTags = append(metaTags, carbon2Tag(label.GetName(), label.GetValue()))
+			}
+
+			_, err := fmt.Fprintf(w, "%s  %s %s %d\n", strings.Join(intrinsicTags, " "), strings.Join(metaTags, " "),
+				formatValue(value), m.GetTimestampMs()/1000)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func carbon2Tag(name string, value string) string {
+	return carbon2TagReplacer.Replace(name) + "=" + carbon2TagReplacer.Replace(value)
+}
@@ -0,0 +1,21 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncodeCarbon2(t *testing.T) {
+	var buf bytes.Buffer
+	err := encodeCarbon2(&buf, sortFamilies(goldenFamilies()), "host1.example.com")
+	assert.NoError(t, err)
+	assertGolden(t, "output.carbon2", buf.Bytes())
+}
+
+func TestCarbon2Tag(t *testing.T) {
+	assert.Equal(t, "interface=br/0.100", carbon2Tag("interface", "br/0.100"))
+	assert.Equal(t, "region=us_west,2", carbon2Tag("region", "us west,2"))
+	assert.Equal(t, "a_b=c_d", carbon2Tag("a=b", "c=d"))
+}
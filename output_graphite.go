@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"regexp"
+	"strings"
+
+	dto "github.com/prometheus/client_model/go"
+	v2 "github.com/sensu/sensu-go/api/core/v2"
+)
+
+const defaultGraphiteTemplate = "{host}.net.{interface}.{metric}"
+
+var (
+	graphitePlaceholderRE = regexp.MustCompile(`\{([a-zA-Z_][a-zA-Z0-9_]*)\}`)
+	graphiteInvalidCharRE = regexp.MustCompile(`[^a-zA-Z0-9_:-]`)
+)
+
+// validateGraphiteTemplate returns an error if the template doesn't reference the metric name, in which case every
+// metric of an interface would share the same path.
+func validateGraphiteTemplate(template string) error {
+	if !strings.Contains(template, "{metric}") {
+		return fmt.Errorf("graphite template %q must contain {metric}", template)
+	}
+	return nil
+}
+
+// encodeGraphitePlaintext writes the families in the Graphite plaintext protocol: "path value epoch". The path is
+// built from the template, where {host} is the host name, {metric} is the family name followed by the Sumo Logic
+// field when present, and any other {name} is the value of the name label. Values are sanitized so that dots and
+// slashes in interface names don't create extra path nodes, and empty nodes are removed.
+func encodeGraphitePlaintext(w io.Writer, families []*dto.MetricFamily, template, host string) error {
+	for _, family := range families {
+		for _, m := range family.Metric {
+			value := getMetricValue(m)
+			if math.IsNaN(value) || math.IsInf(value, 0) {
+				continue
+			}
+
+			path := graphitePath(template, host, family, m)
+			if _, err := fmt.Fprintf(w, "%s %s %d\n", path, formatValue(value), m.GetTimestampMs()/1000); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func graphitePath(template, host string, family *dto.MetricFamily, m *dto.Metric) string {
+	path := graphitePlaceholderRE.ReplaceAllStringFunc(template, func(placeholder string) string {
+		name := placeholder[1 : len(placeholder)-1]
+		switch name {
+		case "host":
+			return sanitizeGraphiteNode(host)
+		case "metric":
+			metric := sanitizeGraphiteNode(family.GetName())
+			if field, ok := getLabelValue(m, fieldLabel); ok {
+				metric += "." + sanitizeGraphiteNode(field)
+			}
+			return metric
+		case interfaceLabel:
+			return sanitizeGraphiteNode(metricInterface(m))
+		default:
+			value, _ := getLabelValue(m, name)
+			return sanitizeGraphiteNode(value)
+		}
+	})
+
+	nodes := strings.Split(path, ".")
+	kept := nodes[:0]
+	for _, node := range nodes {
+		if node != "" {
+			kept = append(kept, node)
+		}
+	}
+	return strings.Join(kept, ".")
+}
+
+func sanitizeGraphiteNode(node string) string {
+	return graphiteInvalidCharRE.ReplaceAllString(node, "_")
+}
+
+// hostName returns the name of the Sensu entity when the event is available, or the host name otherwise.
+func hostName(event *v2.Event) string {
+	if event != nil && event.Entity != nil && event.Entity.Name != "" {
+		return event.Entity.Name
+	}
+	host, err := os.Hostname()
+	if err != nil {
+		return "localhost"
+	}
+	return host
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	dto "github.com/prometheus/client_model/go"
+	v2 "github.com/sensu/sensu-go/api/core/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncodeGraphitePlaintext(t *testing.T) {
+	counters := newMetricFamily("bytes_recv", "", dto.MetricType_COUNTER)
+	newCounterMetric(counters, "eno1", 10858544415, 1639666815123)
+	newCounterMetric(counters, "eno1.100", 1500, 1639666815123)
+	newCounterMetric(counters, "br/0", 2500, 1639666815123)
+	newCounterMetric(counters, sumInterface, 10858548415, 1639666815123)
+	rates := newMetricFamily("bytes_recv_rate", "", dto.MetricType_GAUGE)
+	newGaugeMetric(rates, "eno1", 12.5, 1639666815123)
+	sumo := newMetricFamily(sumoFamilyName, "", dto.MetricType_COUNTER)
+	newSumoCounterMetric(sumo, "bytes_recv", "eno1", 1500, 1639666815123)
+	folded := newMetricFamily(foldedFamilyName, "", dto.MetricType_GAUGE)
+	value, timestamp := float64(3), int64(1639666815123)
+	folded.Metric = append(folded.Metric, &dto.Metric{Gauge: &dto.Gauge{Value: &value}, TimestampMs: &timestamp})
+	families := []*dto.MetricFamily{counters, rates, sumo, folded}
+
+	var buf bytes.Buffer
+	err := encodeGraphitePlaintext(&buf, families, defaultGraphiteTemplate, "host1.example.com")
+	assert.NoError(t, err)
+	assert.Equal(t, `host1_example_com.net.eno1.bytes_recv 10858544415 1639666815
+host1_example_com.net.eno1_100.bytes_recv 1500 1639666815
+host1_example_com.net.br_0.bytes_recv 2500 1639666815
+host1_example_com.net.all.bytes_recv 10858548415 1639666815
+host1_example_com.net.eno1.bytes_recv_rate 12.5 1639666815
+host1_example_com.net.eno1.host_net.bytes_recv 1500 1639666815
+host1_example_com.net.interfaces_folded 3 1639666815
+`, buf.String())
+
+	buf.Reset()
+	err = encodeGraphitePlaintext(&buf, []*dto.MetricFamily{rates}, "servers.{region}.{host}.{metric}.{interface}", "host1")
+	assert.NoError(t, err)
+	assert.Equal(t, "servers.host1.bytes_recv_rate.eno1 12.5 1639666815\n", buf.String())
+}
+
+func TestValidateGraphiteTemplate(t *testing.T) {
+	assert.NoError(t, validateGraphiteTemplate(defaultGraphiteTemplate))
+	assert.NoError(t, validateGraphiteTemplate("{metric}"))
+	assert.Error(t, validateGraphiteTemplate("{host}.net.{interface}"))
+}
+
+func TestHostName(t *testing.T) {
+	event := v2.FixtureEvent("entity1", "network-interface-checks")
+	assert.Equal(t, "entity1", hostName(event))
+
+	host, _ := os.Hostname()
+	assert.Equal(t, host, hostName(nil))
+}
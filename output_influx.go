@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+
+	dto "github.com/prometheus/client_model/go"
+)
+
+var (
+	influxMeasurementEscaper = strings.NewReplacer(`,`, `\,`, ` `, `\ `)
+	influxTagEscaper         = strings.NewReplacer(`,`, `\,`, `=`, `\=`, ` `, `\ `)
+)
+
+// encodeInfluxDBLine writes the families in the InfluxDB line protocol: one measurement per family, the labels as
+// tags, a "value" field and a nanosecond timestamp. Values that can't be represented (NaN, Inf) are skipped.
+func encodeInfluxDBLine(w io.Writer, families []*dto.MetricFamily) error {
+	for _, family := range families {
+		measurement := influxMeasurementEscaper.Replace(family.GetName())
+		for _, m := range family.Metric {
+			value := getMetricValue(m)
+			if math.IsNaN(value) || math.IsInf(value, 0) {
+				continue
+			}
+
+			var line strings.Builder
+			line.WriteString(measurement)
+			for _, label := range sortedLabels(m) {
+				line.WriteString(",")
+				line.WriteString(influxTagEscaper.Replace(label.GetName()))
+				line.WriteString("=")
+				line.WriteString(influxTagEscaper.Replace(label.GetValue()))
+			}
+			line.WriteString(" value=")
+			line.WriteString(formatValue(value))
+			if m.TimestampMs != nil {
+				line.WriteString(fmt.Sprintf(" %d", m.GetTimestampMs()*1000000))
+			}
+			line.WriteString("\n")
+
+			if _, err := io.WriteString(w, line.String()); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// sortedLabels returns the labels of the metric sorted by name, skipping labels with an empty value.
+func sortedLabels(m *dto.Metric) []*dto.LabelPair {
+	labels := make([]*dto.LabelPair, 0, len(m.Label))
+	for _, label := range m.Label {
+		if label.GetValue() != "" {
+			labels = append(labels, label)
+		}
+	}
+	sort.Slice(labels, func(i, j int) bool {
+		return labels[i].GetName() < labels[j].GetName()
+	})
+	return labels
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"bytes"
+	"math"
+	"testing"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncodeInfluxDBLine(t *testing.T) {
+	counters := newMetricFamily("bytes_recv", "", dto.MetricType_COUNTER)
+	newCounterMetric(counters, "eno1", 10858544415, 1639666815123)
+	newCounterMetric(counters, "my iface,1", 1500, 1639666815123)
+	rates := newMetricFamily("bytes_recv_rate", "", dto.MetricType_GAUGE)
+	newGaugeMetric(rates, "eno1", 12.5, 1639666815123)
+	newGaugeMetric(rates, "eno2", math.NaN(), 1639666815123)
+	sumo := newMetricFamily(sumoFamilyName, "", dto.MetricType_COUNTER)
+	m := newSumoCounterMetric(sumo, "bytes_recv", "eno1", 1500, 1639666815123)
+	region, value := "region", "us=west 2"
+	m.Label = append(m.Label, &dto.LabelPair{Name: &region, Value: &value})
+
+	var buf bytes.Buffer
+	err := encodeInfluxDBLine(&buf, []*dto.MetricFamily{counters, rates, sumo})
+	assert.NoError(t, err)
+	assert.Equal(t, `bytes_recv,interface=eno1 value=10858544415 1639666815123000000
+bytes_recv,interface=my\ iface\,1 value=1500 1639666815123000000
+bytes_recv_rate,interface=eno1 value=12.5 1639666815123000000
+host_net,field=bytes_recv,interface=eno1,region=us\=west\ 2 value=1500 1639666815123000000
+`, buf.String())
+}
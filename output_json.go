@@ -0,0 +1,99 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"math"
+	"strings"
+
+	dto "github.com/prometheus/client_model/go"
+)
+
+// jsonDocument is the structured document of the json output format: metrics by interface, with the counter value,
+// its rate and its delta side by side. Families without interface, such as interfaces_folded, are listed under metrics, and
+// labels shared by all metrics (e.g. added with --label) under labels.
+type jsonDocument struct {
+	Timestamp  int64                             `json:"timestamp"`
+	Labels     map[string]string                 `json:"labels,omitempty"`
+	Interfaces map[string]map[string]*jsonMetric `json:"interfaces"`
+	Metrics    map[string]float64                `json:"metrics,omitempty"`
+}
+
+type jsonMetric struct {
+	Value *float64 `json:"value,omitempty"`
+	Rate  *float64 `json:"rate,omitempty"`
+	Delta *float64 `json:"delta,omitempty"`
+}
+
+// encodeJSON writes the families as a JSON document. The Sumo Logic "host_net" family is skipped since it
+// duplicates the counters. Keys are sorted and values that can't be represented in JSON (NaN, Inf) are skipped.
+func encodeJSON(w io.Writer, families []*dto.MetricFamily) error {
+	document := jsonDocument{
+		Labels:     map[string]string{},
+		Interfaces: map[string]map[string]*jsonMetric{},
+		Metrics:    map[string]float64{},
+	}
+
+	familyNames := map[string]struct{}{}
+	for _, family := range families {
+		familyNames[family.GetName()] = struct{}{}
+	}
+
+	for _, family := range families {
+		if family.GetName() == sumoFamilyName {
+			continue
+		}
+
+		// rates and deltas are paired with their counter, when emitted
+		name, suffix := family.GetName(), ""
+		for _, s := range []string{rateFamilySuffix, deltaFamilySuffix} {
+			if counterName := strings.TrimSuffix(name, s); counterName != name {
+				if _, ok := familyNames[counterName]; ok {
+					name, suffix = counterName, s
+					break
+				}
+			}
+		}
+
+		for _, m := range family.Metric {
+			value := getMetricValue(m)
+			if math.IsNaN(value) || math.IsInf(value, 0) {
+				continue
+			}
+			if m.GetTimestampMs() > document.Timestamp {
+				document.Timestamp = m.GetTimestampMs()
+			}
+			for _, label := range m.Label {
+				switch label.GetName() {
+				case interfaceLabel, deviceLabel, fieldLabel:
+				default:
+					document.Labels[label.GetName()] = label.GetValue()
+				}
+			}
+
+			ifName := metricInterface(m)
+			if ifName == "" {
+				document.Metrics[name] = value
+				continue
+			}
+			if document.Interfaces[ifName] == nil {
+				document.Interfaces[ifName] = map[string]*jsonMetric{}
+			}
+			if document.Interfaces[ifName][name] == nil {
+				document.Interfaces[ifName][name] = &jsonMetric{}
+			}
+			switch suffix {
+			case rateFamilySuffix:
+				document.Interfaces[ifName][name].Rate = &value
+			case deltaFamilySuffix:
+				document.Interfaces[ifName][name].Delta = &value
+			default:
+				document.Interfaces[ifName][name].Value = &value
+			}
+		}
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(document)
+}
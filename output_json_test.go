@@ -0,0 +1,44 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncodeJSON(t *testing.T) {
+	var buf bytes.Buffer
+	err := encodeJSON(&buf, sortFamilies(goldenFamilies()))
+	assert.NoError(t, err)
+	assertGolden(t, "output.json", buf.Bytes())
+
+	var document jsonDocument
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &document))
+	assert.Equal(t, float64(10858544415), *document.Interfaces["eno1"]["bytes_recv"].Value)
+	assert.Equal(t, float64(1250), *document.Interfaces["eno1"]["bytes_recv"].Rate)
+	assert.Nil(t, document.Interfaces["br/0.100"]["bytes_recv"].Rate)
+	assert.Nil(t, document.Interfaces["eno1"]["err_in"].Rate)
+	assert.Equal(t, float64(2), document.Metrics[foldedFamilyName])
+	assert.Nil(t, document.Interfaces["eno1"]["bytes_recv"].Delta)
+}
+
+func TestEncodeJSON_Deltas(t *testing.T) {
+	deltas := newMetricFamily("bytes_recv"+deltaFamilySuffix, "", dto.MetricType_GAUGE)
+	newGaugeMetric(deltas, "eno1", 75000, 1639666815123)
+	orphan := newMetricFamily("err_out"+deltaFamilySuffix, "", dto.MetricType_GAUGE)
+	newGaugeMetric(orphan, "eno1", 3, 1639666815123)
+
+	var buf bytes.Buffer
+	err := encodeJSON(&buf, append(goldenFamilies(), deltas, orphan))
+	assert.NoError(t, err)
+
+	var document jsonDocument
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &document))
+	assert.Equal(t, float64(10858544415), *document.Interfaces["eno1"]["bytes_recv"].Value)
+	assert.Equal(t, float64(75000), *document.Interfaces["eno1"]["bytes_recv"].Delta)
+	// a delta without its counter is listed under its own name
+	assert.Equal(t, float64(3), *document.Interfaces["eno1"]["err_out_delta"].Value)
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	dto "github.com/prometheus/client_model/go"
+)
+
+// encodeNagiosPerfdata writes a Nagios plugin output: a status line followed by "|" and the performance data of
+// every metric in the label=value[UOM];warn;crit;min;max format.
+func encodeNagiosPerfdata(w io.Writer, name string, status int, alerts []Alert, families []*dto.MetricFamily,
+	thresholds map[string]*threshold) error {
+	interfaces := map[string]struct{}{}
+	perfdata := make([]string, 0)
+	for _, family := range families {
+		warn, crit := "", ""
+		if t := thresholds[family.GetName()]; t != nil {
+			warn, crit = formatThreshold(t.warning), formatThreshold(t.critical)
+		}
+		for _, m := range family.Metric {
+			ifName := metricInterface(m)
+			if ifName != "" && ifName != sumInterface && ifName != otherInterface {
+				interfaces[ifName] = struct{}{}
+			}
+			uom, min := perfdataUOM(family, m)
+			perfdata = append(perfdata, fmt.Sprintf("%s=%s%s;%s;%s;%s;", perfdataLabel(family, m, ifName),
+				formatValue(getMetricValue(m)), uom, warn, crit, min))
+		}
+	}
+
+	summary := fmt.Sprintf("%d interfaces", len(interfaces))
+	if len(alerts) > 0 {
+		messages := make([]string, 0, len(alerts))
+		for _, alert := range alerts {
+			messages = append(messages, alert.Message)
+		}
+		summary = strings.Join(messages, ", ")
+	}
+
+	_, err := fmt.Fprintf(w, "%s %s - %s | %s\n", strings.ToUpper(name), statusNames[status], summary,
+		strings.Join(perfdata, " "))
+	return err
+}
+
+// metricInterface returns the interface of the metric whatever the naming mode.
+func metricInterface(m *dto.Metric) string {
+	if ifName, ok := getLabelValue(m, interfaceLabel); ok {
+		return ifName
+	}
+	ifName, _ := getLabelValue(m, deviceLabel)
+	return ifName
+}
+
+// perfdataLabel returns the interface_family[_field] label of the metric, quoted when it contains characters
+// that aren't allowed in unquoted labels.
+func perfdataLabel(family *dto.MetricFamily, m *dto.Metric, ifName string) string {
+	parts := make([]string, 0, 3)
+	if ifName != "" {
+		parts = append(parts, ifName)
+	}
+	parts = append(parts, family.GetName())
+	if field, ok := getLabelValue(m, fieldLabel); ok {
+		parts = append(parts, field)
+	}
+
+	label := strings.Join(parts, "_")
+	if strings.ContainsAny(label, " '=") {
+		return "'" + strings.ReplaceAll(label, "'", "''") + "'"
+	}
+	return label
+}
+
+// perfdataUOM returns the unit of measure and the minimum of the metric. Byte counters are in bytes and the other
+// counters are continuous counters, and counters are never negative. Other metrics have no unit and no known minimum.
+func perfdataUOM(family *dto.MetricFamily, m *dto.Metric) (string, string) {
+	if family.GetType() != dto.MetricType_COUNTER {
+		return "", ""
+	}
+	name := family.GetName()
+	if field, ok := getLabelValue(m, fieldLabel); ok {
+		name = field
+	}
+	if strings.Contains(name, "bytes") {
+		return "B", "0"
+	}
+	return "c", "0"
+}
+
+func formatThreshold(value *float64) string {
+	if value == nil {
+		return ""
+	}
+	return formatValue(*value)
+}
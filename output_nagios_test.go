@@ -0,0 +1,49 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/sensu/sensu-plugin-sdk/sensu"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncodeNagiosPerfdata(t *testing.T) {
+	counters := newMetricFamily("bytes_recv", "", dto.MetricType_COUNTER)
+	newCounterMetric(counters, "eno1", 1500, 0)
+	newCounterMetric(counters, "eno2", 2500, 0)
+	rates := newMetricFamily("bytes_recv_rate", "", dto.MetricType_GAUGE)
+	newGaugeMetric(rates, "eno1", 12.5, 0)
+	newGaugeMetric(rates, "my iface", 0, 0)
+	errors := newMetricFamily("err_in", "", dto.MetricType_COUNTER)
+	newCounterMetric(errors, "eno1", 3, 0)
+	sumo := newMetricFamily(sumoFamilyName, "", dto.MetricType_COUNTER)
+	newSumoCounterMetric(sumo, "bytes_recv", "eno1", 1500, 0)
+	newSumoCounterMetric(sumo, "err_in", "eno1", 3, 0)
+	families := []*dto.MetricFamily{counters, rates, errors, sumo}
+	thresholds, _ := parseThresholds(map[string]string{"bytes_recv_rate": "10"}, map[string]string{"bytes_recv_rate": "100"})
+
+	var buf bytes.Buffer
+	err := encodeNagiosPerfdata(&buf, "network-interface-checks", sensu.CheckStateOK, []Alert{}, families, map[string]*threshold{})
+	assert.NoError(t, err)
+	// byte counters are in bytes, and only the counters have a minimum
+	assert.Equal(t, "NETWORK-INTERFACE-CHECKS OK - 3 interfaces | eno1_bytes_recv=1500B;;;0; eno2_bytes_recv=2500B;;;0; "+
+		"eno1_bytes_recv_rate=12.5;;;; 'my iface_bytes_recv_rate'=0;;;; eno1_err_in=3c;;;0; "+
+		"eno1_host_net_bytes_recv=1500B;;;0; eno1_host_net_err_in=3c;;;0;\n", buf.String())
+
+	buf.Reset()
+	alerts := []Alert{{Status: sensu.CheckStateWarning, Message: "bytes_recv_rate on eno1 is 12.5 (>= 10)"}}
+	err = encodeNagiosPerfdata(&buf, "network-interface-checks", sensu.CheckStateWarning, alerts, []*dto.MetricFamily{rates}, thresholds)
+	assert.NoError(t, err)
+	assert.Equal(t, "NETWORK-INTERFACE-CHECKS WARNING - bytes_recv_rate on eno1 is 12.5 (>= 10) | "+
+		"eno1_bytes_recv_rate=12.5;10;100;; 'my iface_bytes_recv_rate'=0;10;100;;\n", buf.String())
+}
+
+func TestPerfdataLabel(t *testing.T) {
+	family := newMetricFamily("bytes_recv", "", dto.MetricType_COUNTER)
+	m := newCounterMetric(family, "eno1", 1, 0)
+	assert.Equal(t, "eno1_bytes_recv", perfdataLabel(family, m, metricInterface(m)))
+	assert.Equal(t, "bytes_recv", perfdataLabel(family, m, ""))
+	assert.Equal(t, "'it''s_bytes_recv'", perfdataLabel(family, m, "it's"))
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strings"
+
+	dto "github.com/prometheus/client_model/go"
+)
+
+const (
+	openMetricsTotalSuffix   = "_total"
+	openMetricsCreatedSuffix = "_created"
+	openMetricsGaugeSuffix   = "_gauge"
+)
+
+var (
+	openMetricsUnits = []string{"bytes", "packets"}
+
+	openMetricsTypes = map[dto.MetricType]string{
+		dto.MetricType_COUNTER:   "counter",
+		dto.MetricType_GAUGE:     "gauge",
+		dto.MetricType_SUMMARY:   "summary",
+		dto.MetricType_UNTYPED:   "unknown",
+		dto.MetricType_HISTOGRAM: "histogram",
+	}
+
+	openMetricsEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+// openMetricsFamily is a family along with its OpenMetrics name and unit.
+type openMetricsFamily struct {
+	family *dto.MetricFamily
+	name   string
+	unit   string
+}
+
+// encodeOpenMetrics writes the families in the OpenMetrics text format. Counter samples get the "_total" suffix
+// and, when known from the state file, a "_created" sample. Byte and packet counters declare their unit, which
+// OpenMetrics requires to be the suffix of the family name, so e.g. bytes_recv is exposed as recv_bytes_total.
+// The exposition ends with "# EOF".
+func encodeOpenMetrics(w io.Writer, families []*dto.MetricFamily, createdMS map[*dto.Metric]int64) error {
+	for _, omFamily := range openMetricsFamilies(families) {
+		if err := encodeOpenMetricsFamily(w, omFamily, createdMS); err != nil {
+			return err
+		}
+	}
+
+	_, err := io.WriteString(w, "# EOF\n")
+	return err
+}
+
+// openMetricsFamilies computes the OpenMetrics names of the families. A family whose name collides with the name
+// or a sample of another family, e.g. a gauge named like the "_total" or "_created" sample of a counter, is renamed
+// with the "_gauge" suffix since OpenMetrics doesn't allow such collisions.
+func openMetricsFamilies(families []*dto.MetricFamily) []*openMetricsFamily {
+	omFamilies := make([]*openMetricsFamily, 0, len(families))
+	reserved := map[string]struct{}{}
+	for _, family := range families {
+		omFamily := &openMetricsFamily{family: family, name: family.GetName()}
+		if family.GetType() == dto.MetricType_COUNTER {
+			omFamily.name = strings.TrimSuffix(omFamily.name, openMetricsTotalSuffix)
+			omFamily.name, omFamily.unit = openMetricsUnit(omFamily.name)
+			reserved[omFamily.name+openMetricsTotalSuffix] = struct{}{}
+			reserved[omFamily.name+openMetricsCreatedSuffix] = struct{}{}
+		}
+		omFamilies = append(omFamilies, omFamily)
+	}
+
+	for _, omFamily := range omFamilies {
+		if omFamily.family.GetType() == dto.MetricType_COUNTER {
+			continue
+		}
+		if _, ok := reserved[omFamily.name]; ok {
+			omFamily.name += openMetricsGaugeSuffix
+		}
+		reserved[omFamily.name] = struct{}{}
+	}
+
+	return omFamilies
+}
+
+// openMetricsUnit returns the name of the counter with its unit moved to the end of the name, and the unit.
+func openMetricsUnit(name string) (string, string) {
+	parts := strings.Split(name, "_")
+	for _, unit := range openMetricsUnits {
+		if parts[len(parts)-1] == unit {
+			return name, unit
+		}
+		for i, part := range parts {
+			if part == unit {
+				parts = append(append(parts[:i:i], parts[i+1:]...), unit)
+				return strings.Join(parts, "_"), unit
+			}
+		}
+	}
+	return name, ""
+}
+
+func encodeOpenMetricsFamily(w io.Writer, omFamily *openMetricsFamily, createdMS map[*dto.Metric]int64) error {
+	family, name := omFamily.family, omFamily.name
+	header := fmt.Sprintf("# TYPE %s %s\n", name, openMetricsTypes[family.GetType()])
+	if omFamily.unit != "" {
+		header += fmt.Sprintf("# UNIT %s %s\n", name, omFamily.unit)
+	}
+	if family.GetHelp() != "" {
+		header += fmt.Sprintf("# HELP %s %s\n", name, openMetricsEscaper.Replace(family.GetHelp()))
+	}
+	if _, err := io.WriteString(w, header); err != nil {
+		return err
+	}
+
+	for _, m := range family.Metric {
+		labels := openMetricsLabels(m)
+		timestamp := ""
+		if m.TimestampMs != nil {
+			timestamp = " " + openMetricsTimestamp(m.GetTimestampMs())
+		}
+
+		if family.GetType() != dto.MetricType_COUNTER {
+			if _, err := fmt.Fprintf(w, "%s%s %s%s\n", name, labels, openMetricsValue(getMetricValue(m)), timestamp); err != nil {
+				return err
+			}
+			continue
+		}
+
+		_, err := fmt.Fprintf(w, "%s%s%s %s%s\n", name, openMetricsTotalSuffix, labels,
+			openMetricsValue(getMetricValue(m)), timestamp)
+		if err != nil {
+			return err
+		}
+		if created, ok := createdMS[m]; ok {
+			_, err = fmt.Fprintf(w, "%s%s%s %s%s\n", name, openMetricsCreatedSuffix, labels,
+				openMetricsTimestamp(created), timestamp)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func openMetricsLabels(m *dto.Metric) string {
+	if len(m.Label) == 0 {
+		return ""
+	}
+	labels := make([]string, 0, len(m.Label))
+	for _, label := range m.Label {
+		labels = append(labels, fmt.Sprintf(`%s="%s"`, label.GetName(), openMetricsEscaper.Replace(label.GetValue())))
+	}
+	return "{" + strings.Join(labels, ",") + "}"
+}
+
+func openMetricsValue(value float64) string {
+	switch {
+	case math.IsNaN(value):
+		return "NaN"
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	}
+	return formatValue(value)
+}
+
+// openMetricsTimestamp returns the millisecond timestamp in seconds, as OpenMetrics timestamps are.
+func openMetricsTimestamp(timestampMS int64) string {
+	return fmt.Sprintf("%d.%03d", timestampMS/1000, timestampMS%1000)
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncodeOpenMetrics(t *testing.T) {
+	families := sortFamilies(goldenFamilies())
+	createdMS := map[*dto.Metric]int64{}
+	for _, family := range families {
+		if family.GetType() != dto.MetricType_COUNTER {
+			continue
+		}
+		for _, m := range family.Metric {
+			if metricInterface(m) != sumInterface {
+				createdMS[m] = 1639000000456
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	err := encodeOpenMetrics(&buf, families, createdMS)
+	assert.NoError(t, err)
+	assertGolden(t, "output.openmetrics", buf.Bytes())
+}
+
+func TestOpenMetricsFamilies(t *testing.T) {
+	counter := newMetricFamily("node_network_receive_bytes_total", "", dto.MetricType_COUNTER)
+	created := newMetricFamily("node_network_receive_bytes_created", "", dto.MetricType_GAUGE)
+	total := newMetricFamily("packets_sent", "", dto.MetricType_COUNTER)
+	collision := newMetricFamily("sent_packets_total", "", dto.MetricType_GAUGE)
+	rate := newMetricFamily("packets_sent_rate", "", dto.MetricType_GAUGE)
+
+	omFamilies := openMetricsFamilies([]*dto.MetricFamily{counter, created, total, collision, rate})
+	names := make([]string, 0, len(omFamilies))
+	units := make([]string, 0, len(omFamilies))
+	for _, omFamily := range omFamilies {
+		names = append(names, omFamily.name)
+		units = append(units, omFamily.unit)
+	}
+	assert.Equal(t, []string{"node_network_receive_bytes", "node_network_receive_bytes_created_gauge", "sent_packets",
+		"sent_packets_total_gauge", "packets_sent_rate"}, names)
+	assert.Equal(t, []string{"bytes", "", "packets", "", ""}, units)
+}
+
+func TestOpenMetricsUnit(t *testing.T) {
+	testCases := []struct {
+		name         string
+		expectedName string
+		expectedUnit string
+	}{
+		{"bytes_recv", "recv_bytes", "bytes"},
+		{"packets_sent", "sent_packets", "packets"},
+		{"sensu_net_bytes_sent", "sensu_net_sent_bytes", "bytes"},
+		{"node_network_transmit_packets", "node_network_transmit_packets", "packets"},
+		{"err_in", "err_in", ""},
+		{sumoFamilyName, sumoFamilyName, ""},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			name, unit := openMetricsUnit(testCase.name)
+			assert.Equal(t, testCase.expectedName, name)
+			assert.Equal(t, testCase.expectedUnit, unit)
+		})
+	}
+}
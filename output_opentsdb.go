@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"strings"
+
+	dto "github.com/prometheus/client_model/go"
+)
+
+var openTSDBInvalidCharRE = regexp.MustCompile(`[^a-zA-Z0-9_./-]`)
+
+// encodeOpenTSDBLine writes the families in the OpenTSDB telnet line format: "put metric epoch value tag=v ...".
+// The labels are written as tags sorted by name, along with a host tag since OpenTSDB requires at least one tag.
+// Characters that OpenTSDB doesn't allow in metric names and tags are replaced with "_".
+func encodeOpenTSDBLine(w io.Writer, families []*dto.MetricFamily, host string) error {
+	hostLabel, hostValue := "host", host
+	hostTag := &dto.LabelPair{Name: &hostLabel, Value: &hostValue}
+	for _, family := range families {
+		metric := sanitizeOpenTSDB(family.GetName())
+		for _, m := range family.Metric {
+			value := getMetricValue(m)
+			if math.IsNaN(value) || math.IsInf(value, 0) {
+				continue
+			}
+
+			labels := sortedLabels(m)
+			if _, ok := getLabelValue(m, hostLabel); !ok {
+				labels = sortedLabels(&dto.Metric{Label: append(labels, hostTag)})
+			}
+			tags := make([]string, 0, len(labels))
+			for _, label := range labels {
+				tags = append(tags, sanitizeOpenTSDB(label.GetName())+"="+sanitizeOpenTSDB(label.GetValue()))
+			}
+
+			_, err := fmt.Fprintf(w, "put %s %d %s %s\n", metric, m.GetTimestampMs()/1000, formatValue(value),
+				strings.Join(tags, " "))
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func sanitizeOpenTSDB(value string) string {
+	return openTSDBInvalidCharRE.ReplaceAllString(value, "_")
+}
@@ -0,0 +1,21 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncodeOpenTSDBLine(t *testing.T) {
+	var buf bytes.Buffer
+	err := encodeOpenTSDBLine(&buf, sortFamilies(goldenFamilies()), "host1.example.com")
+	assert.NoError(t, err)
+	assertGolden(t, "output.opentsdb", buf.Bytes())
+}
+
+func TestSanitizeOpenTSDB(t *testing.T) {
+	assert.Equal(t, "br/0.100", sanitizeOpenTSDB("br/0.100"))
+	assert.Equal(t, "us_west_2", sanitizeOpenTSDB("us west,2"))
+	assert.Equal(t, "a_b", sanitizeOpenTSDB("a=b"))
+}
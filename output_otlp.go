@@ -0,0 +1,210 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	dto "github.com/prometheus/client_model/go"
+	v2 "github.com/sensu/sensu-go/api/core/v2"
+)
+
+const (
+	otlpMetricsPath = "/v1/metrics"
+
+	// otlpCumulative is the AGGREGATION_TEMPORALITY_CUMULATIVE value of the OTLP AggregationTemporality enum
+	otlpCumulative = 2
+
+	// otlpAggregateSuffix is appended to the name of the counters of the interface="all" and interface="other"
+	// aggregates, which are not monotonic
+	otlpAggregateSuffix = "_aggregate"
+)
+
+var headerNameRE = regexp.MustCompile("^[A-Za-z0-9!#$%&'*+.^_`|~-]+$")
+
+// The types below are the subset of the OTLP ExportMetricsServiceRequest message used by the exporter, in the OTLP
+// JSON encoding where 64 bits integers are strings.
+type otlpRequest struct {
+	ResourceMetrics []otlpResourceMetrics `json:"resourceMetrics"`
+}
+
+type otlpResourceMetrics struct {
+	Resource     otlpResource       `json:"resource"`
+	ScopeMetrics []otlpScopeMetrics `json:"scopeMetrics"`
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes"`
+}
+
+type otlpScopeMetrics struct {
+	Scope   otlpScope    `json:"scope"`
+	Metrics []otlpMetric `json:"metrics"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpMetric struct {
+	Name        string     `json:"name"`
+	Description string     `json:"description,omitempty"`
+	Sum         *otlpSum   `json:"sum,omitempty"`
+	Gauge       *otlpGauge `json:"gauge,omitempty"`
+}
+
+type otlpSum struct {
+	DataPoints             []otlpDataPoint `json:"dataPoints"`
+	AggregationTemporality int             `json:"aggregationTemporality"`
+	IsMonotonic            bool            `json:"isMonotonic"`
+}
+
+type otlpGauge struct {
+	DataPoints []otlpDataPoint `json:"dataPoints"`
+}
+
+type otlpDataPoint struct {
+	Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano,omitempty"`
+	TimeUnixNano      string         `json:"timeUnixNano"`
+	AsDouble          float64        `json:"asDouble"`
+}
+
+type otlpKeyValue struct {
+	Key   string          `json:"key"`
+	Value otlpStringValue `json:"value"`
+}
+
+type otlpStringValue struct {
+	StringValue string `json:"stringValue"`
+}
+
+// otlpMetricsURL returns the URL metrics are exported to, the endpoint followed by /v1/metrics unless it already
+// ends with it.
+func otlpMetricsURL(endpoint string) string {
+	endpoint = strings.TrimSuffix(endpoint, "/")
+	if strings.HasSuffix(endpoint, otlpMetricsPath) {
+		return endpoint
+	}
+	return endpoint + otlpMetricsPath
+}
+
+// otlpResourceAttributes returns the resource attributes of the exported metrics: the host name, and the Sensu entity
+// and namespace when the event is available.
+func otlpResourceAttributes(event *v2.Event) []otlpKeyValue {
+	attributes := []otlpKeyValue{
+		newOTLPKeyValue("host.name", hostName(event)),
+		newOTLPKeyValue("service.name", plugin.Name),
+	}
+	if event != nil && event.Entity != nil {
+		attributes = append(attributes,
+			newOTLPKeyValue("sensu.entity.name", event.Entity.Name),
+			newOTLPKeyValue("sensu.namespace", event.Entity.Namespace))
+	}
+	return attributes
+}
+
+func newOTLPKeyValue(key, value string) otlpKeyValue {
+	return otlpKeyValue{Key: key, Value: otlpStringValue{StringValue: value}}
+}
+
+// newOTLPRequest maps the families to OTLP metrics: counters are monotonic cumulative sums starting at their creation
+// timestamp from the state file, other families are gauges. The interface="all" and interface="other" counters are
+// non-monotonic sums, in a metric of their own named with the _aggregate suffix, since they drop when an interface
+// disappears or stops being folded. Metric labels, such as interface, are data point attributes. The Sumo Logic
+// "host_net" family is skipped since it duplicates the counters, and values that can't be represented in JSON (NaN,
+// Inf) are skipped.
+func newOTLPRequest(families []*dto.MetricFamily, createdMS map[*dto.Metric]int64, resource []otlpKeyValue) *otlpRequest {
+	metrics := make([]otlpMetric, 0, len(families))
+	for _, family := range families {
+		if family.GetName() == sumoFamilyName {
+			continue
+		}
+
+		dataPoints := make([]otlpDataPoint, 0, len(family.Metric))
+		aggregateDataPoints := make([]otlpDataPoint, 0)
+		for _, m := range family.Metric {
+			value := getMetricValue(m)
+			if math.IsNaN(value) || math.IsInf(value, 0) {
+				continue
+			}
+			dataPoint := otlpDataPoint{
+				TimeUnixNano: unixNano(m.GetTimestampMs()),
+				AsDouble:     value,
+			}
+			for _, label := range m.Label {
+				dataPoint.Attributes = append(dataPoint.Attributes, newOTLPKeyValue(label.GetName(), label.GetValue()))
+			}
+			if created, ok := createdMS[m]; ok && family.GetType() == dto.MetricType_COUNTER {
+				dataPoint.StartTimeUnixNano = unixNano(created)
+			}
+			if ifName, _ := getLabelValue(m, interfaceLabel); family.GetType() == dto.MetricType_COUNTER &&
+				(ifName == sumInterface || ifName == otherInterface) {
+				aggregateDataPoints = append(aggregateDataPoints, dataPoint)
+				continue
+			}
+			dataPoints = append(dataPoints, dataPoint)
+		}
+
+		if len(dataPoints) > 0 {
+			metric := otlpMetric{Name: family.GetName(), Description: family.GetHelp()}
+			if family.GetType() == dto.MetricType_COUNTER {
+				metric.Sum = &otlpSum{DataPoints: dataPoints, AggregationTemporality: otlpCumulative, IsMonotonic: true}
+			} else {
+				metric.Gauge = &otlpGauge{DataPoints: dataPoints}
+			}
+			metrics = append(metrics, metric)
+		}
+		if len(aggregateDataPoints) > 0 {
+			metrics = append(metrics, otlpMetric{
+				Name:        family.GetName() + otlpAggregateSuffix,
+				Description: family.GetHelp(),
+				Sum:         &otlpSum{DataPoints: aggregateDataPoints, AggregationTemporality: otlpCumulative},
+			})
+		}
+	}
+
+	return &otlpRequest{
+		ResourceMetrics: []otlpResourceMetrics{{
+			Resource: otlpResource{Attributes: resource},
+			ScopeMetrics: []otlpScopeMetrics{{
+				Scope:   otlpScope{Name: plugin.Name},
+				Metrics: metrics,
+			}},
+		}},
+	}
+}
+
+func unixNano(timestampMS int64) string {
+	return strconv.FormatInt(timestampMS*1e6, 10)
+}
+
+// exportOTLP exports the families to the OTLP/HTTP endpoint in the JSON encoding, with the additional headers. An
+// Authorization header takes precedence over the push credentials.
+func (p *pusher) exportOTLP(endpoint string, headers map[string]string, families []*dto.MetricFamily,
+	createdMS map[*dto.Metric]int64, event *v2.Event) error {
+	body, err := json.Marshal(newOTLPRequest(families, createdMS, otlpResourceAttributes(event)))
+	if err != nil {
+		return fmt.Errorf("error encoding OTLP metrics: %v", err)
+	}
+
+	header := http.Header{"Content-Type": []string{"application/json"}}
+	for name, value := range headers {
+		header.Set(name, value)
+	}
+	return p.send(http.MethodPost, otlpMetricsURL(endpoint), header, body)
+}
+
+// validateOTLPHeaders checks that the additional headers are valid HTTP header names.
+func validateOTLPHeaders(headers map[string]string) error {
+	for name := range headers {
+		if !headerNameRE.MatchString(name) {
+			return fmt.Errorf("invalid --otlp-header name %q", name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	v2 "github.com/sensu/sensu-go/api/core/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// otlpMock is the JSON document received by the mock OTLP receiver, decoded independently of the exporter types.
+type otlpMock struct {
+	ResourceMetrics []struct {
+		Resource struct {
+			Attributes []otlpMockKeyValue `json:"attributes"`
+		} `json:"resource"`
+		ScopeMetrics []struct {
+			Scope struct {
+				Name string `json:"name"`
+			} `json:"scope"`
+			Metrics []struct {
+				Name string `json:"name"`
+				Sum  *struct {
+					DataPoints             []otlpMockDataPoint `json:"dataPoints"`
+					AggregationTemporality int                 `json:"aggregationTemporality"`
+					IsMonotonic            bool                `json:"isMonotonic"`
+				} `json:"sum"`
+				Gauge *struct {
+					DataPoints []otlpMockDataPoint `json:"dataPoints"`
+				} `json:"gauge"`
+			} `json:"metrics"`
+		} `json:"scopeMetrics"`
+	} `json:"resourceMetrics"`
+}
+
+type otlpMockKeyValue struct {
+	Key   string `json:"key"`
+	Value struct {
+		StringValue string `json:"stringValue"`
+	} `json:"value"`
+}
+
+type otlpMockDataPoint struct {
+	Attributes        []otlpMockKeyValue `json:"attributes"`
+	StartTimeUnixNano string             `json:"startTimeUnixNano"`
+	TimeUnixNano      string             `json:"timeUnixNano"`
+	AsDouble          float64            `json:"asDouble"`
+}
+
+func otlpAttributes(keyValues []otlpMockKeyValue) map[string]string {
+	attributes := map[string]string{}
+	for _, kv := range keyValues {
+		attributes[kv.Key] = kv.Value.StringValue
+	}
+	return attributes
+}
+
+func TestPusher_ExportOTLP(t *testing.T) {
+	tmpFile := filepath.Join(t.TempDir(), uuid.New().String()+".json")
+	collector, err := NewCollector(CollectorOptions{Sum: true, SumoLogic: true, StateFile: tmpFile, MaxRateIntervalSeconds: 60})
+	assert.NoError(t, err)
+	_, err = collector.Collect(GetNetStatsMock1)
+	assert.NoError(t, err)
+	time.Sleep(10 * time.Millisecond)
+	collector, err = NewCollector(CollectorOptions{Sum: true, SumoLogic: true, StateFile: tmpFile, MaxRateIntervalSeconds: 60})
+	assert.NoError(t, err)
+	families, err := collector.Collect(GetNetStatsMock2)
+	assert.NoError(t, err)
+	firstNS := strconv.FormatInt(collector.createdMS[familiesByName(families)["bytes_sent"].Metric[0]]*1e6, 10)
+
+	server, requests := newPushServer(t, http.StatusOK)
+	p := &pusher{client: server.Client()}
+	event := v2.FixtureEvent("entity1", "check1")
+	assert.NoError(t, p.exportOTLP(server.URL, map[string]string{"X-Scope-OrgID": "tenant1"}, families,
+		collector.createdMS, event))
+
+	assert.Len(t, *requests, 1)
+	request := (*requests)[0]
+	assert.Equal(t, http.MethodPost, request.method)
+	assert.Equal(t, otlpMetricsPath, request.path)
+	assert.Equal(t, "application/json", request.header.Get("Content-Type"))
+	assert.Equal(t, "tenant1", request.header.Get("X-Scope-OrgID"))
+
+	var received otlpMock
+	assert.NoError(t, json.Unmarshal(request.body, &received))
+	assert.Len(t, received.ResourceMetrics, 1)
+	resource := otlpAttributes(received.ResourceMetrics[0].Resource.Attributes)
+	assert.Equal(t, "entity1", resource["host.name"])
+	assert.Equal(t, "entity1", resource["sensu.entity.name"])
+	assert.Equal(t, "default", resource["sensu.namespace"])
+
+	assert.Len(t, received.ResourceMetrics[0].ScopeMetrics, 1)
+	metrics := received.ResourceMetrics[0].ScopeMetrics[0].Metrics
+	var names []string
+	for _, metric := range metrics {
+		names = append(names, metric.Name)
+	}
+	// host_net duplicates the counters
+	// the interface="all" counters are a metric of their own
+	assert.Equal(t, []string{"bits_sent_rate", "bytes_sent", "bytes_sent_aggregate", "bytes_sent_rate", "err_in",
+		"err_in_aggregate", "err_in_rate"}, names)
+
+	bytesSent := metrics[1]
+	assert.Nil(t, bytesSent.Gauge)
+	assert.True(t, bytesSent.Sum.IsMonotonic)
+	assert.Equal(t, otlpCumulative, bytesSent.Sum.AggregationTemporality)
+	assert.Len(t, bytesSent.Sum.DataPoints, 2)
+	assert.Equal(t, map[string]string{interfaceLabel: "eno1"}, otlpAttributes(bytesSent.Sum.DataPoints[0].Attributes))
+	assert.Equal(t, float64(22345676), bytesSent.Sum.DataPoints[0].AsDouble)
+	assert.Equal(t, firstNS, bytesSent.Sum.DataPoints[0].StartTimeUnixNano)
+	assert.NotEqual(t, firstNS, bytesSent.Sum.DataPoints[0].TimeUnixNano)
+
+	// the interface="all" sum drops when an interface disappears, and has no creation timestamp
+	bytesSentAll := metrics[2]
+	assert.Nil(t, bytesSentAll.Gauge)
+	assert.False(t, bytesSentAll.Sum.IsMonotonic)
+	assert.Equal(t, otlpCumulative, bytesSentAll.Sum.AggregationTemporality)
+	assert.Len(t, bytesSentAll.Sum.DataPoints, 1)
+	assert.Equal(t, map[string]string{interfaceLabel: sumInterface},
+		otlpAttributes(bytesSentAll.Sum.DataPoints[0].Attributes))
+	assert.Equal(t, float64(22345676+33435678), bytesSentAll.Sum.DataPoints[0].AsDouble)
+	assert.Equal(t, "", bytesSentAll.Sum.DataPoints[0].StartTimeUnixNano)
+
+	bytesSentRate := metrics[3]
+	assert.Nil(t, bytesSentRate.Sum)
+	assert.Len(t, bytesSentRate.Gauge.DataPoints, 3)
+	assert.Equal(t, "", bytesSentRate.Gauge.DataPoints[0].StartTimeUnixNano)
+}
+
+func TestPusher_ExportOTLPAuthorization(t *testing.T) {
+	server, requests := newPushServer(t, http.StatusOK)
+	p := &pusher{client: server.Client(), bearerToken: "token"}
+	assert.NoError(t, p.exportOTLP(server.URL, map[string]string{"Authorization": "Api-Token x"}, goldenFamilies(),
+		nil, nil))
+	assert.NoError(t, p.exportOTLP(server.URL, map[string]string{}, goldenFamilies(), nil, nil))
+
+	assert.Len(t, *requests, 2)
+	// the explicit header wins over the push credentials
+	assert.Equal(t, "Api-Token x", (*requests)[0].header.Get("Authorization"))
+	assert.Equal(t, "Bearer token", (*requests)[1].header.Get("Authorization"))
+}
+
+func TestNewOTLPRequest_SkipsNaN(t *testing.T) {
+	request := newOTLPRequest(goldenFamilies(), nil, otlpResourceAttributes(nil))
+
+	metrics := request.ResourceMetrics[0].ScopeMetrics[0].Metrics
+	assert.Equal(t, "bytes_recv_rate", metrics[0].Name)
+	assert.Len(t, metrics[0].Gauge.DataPoints, 3)
+	assert.Equal(t, "", metrics[2].Sum.DataPoints[0].StartTimeUnixNano)
+	_, err := json.Marshal(request)
+	assert.NoError(t, err)
+}
+
+func TestNewOTLPRequest_UniqueNames(t *testing.T) {
+	families := goldenFamilies()
+	newCounterMetric(familiesByName(families)["err_in"], otherInterface, 5, 1639666815123)
+	request := newOTLPRequest(families, nil, otlpResourceAttributes(nil))
+
+	var names []string
+	for _, metric := range request.ResourceMetrics[0].ScopeMetrics[0].Metrics {
+		assert.NotContains(t, names, metric.Name)
+		names = append(names, metric.Name)
+	}
+	assert.Equal(t, []string{"bytes_recv_rate", foldedFamilyName, "bytes_recv", "bytes_recv_aggregate", "err_in",
+		"err_in_aggregate"}, names)
+}
+
+func TestOTLPMetricsURL(t *testing.T) {
+	assert.Equal(t, "http://localhost:4318/v1/metrics", otlpMetricsURL("http://localhost:4318"))
+	assert.Equal(t, "http://localhost:4318/v1/metrics", otlpMetricsURL("http://localhost:4318/"))
+	assert.Equal(t, "https://otlp.example.com/otlp/v1/metrics", otlpMetricsURL("https://otlp.example.com/otlp"))
+	assert.Equal(t, "http://localhost:4318/v1/metrics", otlpMetricsURL("http://localhost:4318/v1/metrics"))
+}
+
+func TestValidateOTLPHeaders(t *testing.T) {
+	assert.NoError(t, validateOTLPHeaders(map[string]string{"Authorization": "Api-Token x", "X-Scope-OrgID": "1"}))
+	assert.Error(t, validateOTLPHeaders(map[string]string{"X Scope": "1"}))
+	assert.Error(t, validateOTLPHeaders(map[string]string{"": "1"}))
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/golang/snappy"
+	dto "github.com/prometheus/client_model/go"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+const (
+	defaultPushJob     = "network_interface_checks"
+	defaultPushRetries = 3
+	defaultPushTimeout = 30
+
+	jobLabel      = "job"
+	instanceLabel = "instance"
+	nameLabel     = "__name__"
+)
+
+// pushBackoff is the delay before the first retry of a push, doubled on each retry.
+var pushBackoff = time.Second
+
+// pusher sends the encoded families to a push endpoint, with authentication and retries.
+type pusher struct {
+	client      *http.Client
+	username    string
+	password    string
+	bearerToken string
+	retries     int
+	// deadline is the time the pushes must complete by, including their retries, none when zero.
+	deadline time.Time
+}
+
+// validatePushURL checks that the push endpoint URL is an absolute http or https URL.
+func validatePushURL(flag, pushURL string) error {
+	u, err := url.Parse(pushURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("--%s must be an http or https URL", flag)
+	}
+	return nil
+}
+
+// send sends the body to the URL, retrying on connection errors, 429 and 5xx responses. A retry that would start after
+// the deadline is not attempted, and the requests are canceled at the deadline.
+func (p *pusher) send(method, pushURL string, header http.Header, body []byte) error {
+	var err error
+	backoff := pushBackoff
+	for attempt := 0; attempt <= p.retries; attempt++ {
+		if attempt > 0 {
+			if !p.deadline.IsZero() && time.Now().Add(backoff).After(p.deadline) {
+				return fmt.Errorf("%v, no retry before the push timeout", err)
+			}
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+
+		var retry bool
+		retry, err = p.sendOnce(method, pushURL, header, body)
+		if err == nil || !retry {
+			return err
+		}
+	}
+	return err
+}
+
+func (p *pusher) sendOnce(method, pushURL string, header http.Header, body []byte) (bool, error) {
+	ctx := context.Background()
+	if !p.deadline.IsZero() {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, p.deadline)
+		defer cancel()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, pushURL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	// an Authorization header set explicitly, e.g. with --otlp-header, wins over the credentials
+	if req.Header.Get("Authorization") == "" {
+		if p.bearerToken != "" {
+			req.Header.Set("Authorization", "Bearer "+p.bearerToken)
+		} else if p.username != "" {
+			req.SetBasicAuth(p.username, p.password)
+		}
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return true, fmt.Errorf("error pushing metrics to %s: %v", pushURL, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode/100 == 2 {
+		_, _ = io.Copy(ioutil.Discard, resp.Body)
+		return false, nil
+	}
+	message, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+	err = fmt.Errorf("error pushing metrics to %s: %s: %s", pushURL, resp.Status, strings.TrimSpace(string(message)))
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode/100 == 5, err
+}
+
+// pushToGateway replaces the metrics of the job and instance grouping key on the Pushgateway with the families, in
+// the Prometheus text format without timestamps as the Pushgateway rejects them.
+func (p *pusher) pushToGateway(gatewayURL, job, instance string, families []*dto.MetricFamily) error {
+	var body bytes.Buffer
+	if err := encodePrometheusText(&body, nil, withoutTimestamps(families)); err != nil {
+		return err
+	}
+
+	pushURL := strings.TrimSuffix(gatewayURL, "/") + "/metrics" + groupingKeyPath(jobLabel, job) +
+		groupingKeyPath(instanceLabel, instance)
+	header := http.Header{"Content-Type": []string{"text/plain; version=0.0.4"}}
+	return p.send(http.MethodPut, pushURL, header, body.Bytes())
+}
+
+// groupingKeyPath returns the URL path of a Pushgateway grouping key label. Values that are empty or contain a slash
+// are base64 encoded.
+func groupingKeyPath(name, value string) string {
+	if value == "" || strings.Contains(value, "/") {
+		return "/" + name + "@base64/" + base64.RawURLEncoding.EncodeToString([]byte(value))
+	}
+	return "/" + name + "/" + url.PathEscape(value)
+}
+
+// pushRemoteWrite sends the families to a Prometheus remote-write endpoint, as a snappy compressed WriteRequest. The
+// job and instance labels are added to the series that don't have them already.
+func (p *pusher) pushRemoteWrite(writeURL, job, instance string, families []*dto.MetricFamily, nowMS int64) error {
+	body := snappy.Encode(nil, encodeWriteRequest(families, map[string]string{jobLabel: job, instanceLabel: instance}, nowMS))
+	header := http.Header{
+		"Content-Encoding":                  []string{"snappy"},
+		"Content-Type":                      []string{"application/x-protobuf"},
+		"X-Prometheus-Remote-Write-Version": []string{"0.1.0"},
+	}
+	return p.send(http.MethodPost, writeURL, header, body)
+}
+
+// encodeWriteRequest encodes the families as a remote-write WriteRequest protobuf message, one time series per metric
+// with its non-empty labels sorted by name. Metrics without timestamp are sampled at nowMS.
+//
+//	message WriteRequest { repeated TimeSeries timeseries = 1; }
+//	message TimeSeries { repeated Label labels = 1; repeated Sample samples = 2; }
+//	message Label { string name = 1; string value = 2; }
+//	message Sample { double value = 1; int64 timestamp = 2; }
+func encodeWriteRequest(families []*dto.MetricFamily, extraLabels map[string]string, nowMS int64) []byte {
+	var request []byte
+	for _, family := range families {
+		for _, m := range family.Metric {
+			labels := map[string]string{nameLabel: family.GetName()}
+			for name, value := range extraLabels {
+				labels[name] = value
+			}
+			for _, label := range m.Label {
+				labels[label.GetName()] = label.GetValue()
+			}
+			names := make([]string, 0, len(labels))
+			for name, value := range labels {
+				if value != "" {
+					names = append(names, name)
+				}
+			}
+			sort.Strings(names)
+
+			var series []byte
+			for _, name := range names {
+				var label []byte
+				label = protowire.AppendTag(label, 1, protowire.BytesType)
+				label = protowire.AppendString(label, name)
+				label = protowire.AppendTag(label, 2, protowire.BytesType)
+				label = protowire.AppendString(label, labels[name])
+				series = protowire.AppendTag(series, 1, protowire.BytesType)
+				series = protowire.AppendBytes(series, label)
+			}
+
+			timestampMS := nowMS
+			if m.TimestampMs != nil {
+				timestampMS = m.GetTimestampMs()
+			}
+			var sample []byte
+			sample = protowire.AppendTag(sample, 1, protowire.Fixed64Type)
+			sample = protowire.AppendFixed64(sample, math.Float64bits(getMetricValue(m)))
+			sample = protowire.AppendTag(sample, 2, protowire.VarintType)
+			sample = protowire.AppendVarint(sample, uint64(timestampMS))
+			series = protowire.AppendTag(series, 2, protowire.BytesType)
+			series = protowire.AppendBytes(series, sample)
+
+			request = protowire.AppendTag(request, 1, protowire.BytesType)
+			request = protowire.AppendBytes(request, series)
+		}
+	}
+	return request
+}
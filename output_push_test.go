@@ -0,0 +1,213 @@
+package main
+
+import (
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+type pushRequest struct {
+	method string
+	path   string
+	header http.Header
+	body   []byte
+}
+
+// newPushServer returns a push endpoint stand-in recording the requests and answering with the statuses in turn, the
+// last one being repeated.
+func newPushServer(t *testing.T, statuses ...int) (*httptest.Server, *[]pushRequest) {
+	requests := &[]pushRequest{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		assert.NoError(t, err)
+		*requests = append(*requests, pushRequest{method: r.Method, path: r.URL.EscapedPath(), header: r.Header, body: body})
+		status := statuses[len(statuses)-1]
+		if len(*requests) <= len(statuses) {
+			status = statuses[len(*requests)-1]
+		}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+	return server, requests
+}
+
+type writeSeries struct {
+	labels      []string
+	value       float64
+	timestampMS int64
+}
+
+// decodeWriteRequest decodes the time series of a snappy compressed remote-write WriteRequest.
+func decodeWriteRequest(t *testing.T, body []byte) []writeSeries {
+	request, err := snappy.Decode(nil, body)
+	assert.NoError(t, err)
+
+	var series []writeSeries
+	forEachField(t, request, func(_ protowire.Number, seriesBytes []byte, _ uint64) {
+		var s writeSeries
+		forEachField(t, seriesBytes, func(num protowire.Number, b []byte, _ uint64) {
+			if num == 1 {
+				var label []string
+				forEachField(t, b, func(_ protowire.Number, value []byte, _ uint64) {
+					label = append(label, string(value))
+				})
+				s.labels = append(s.labels, strings.Join(label, "="))
+				return
+			}
+			forEachField(t, b, func(num protowire.Number, _ []byte, v uint64) {
+				if num == 1 {
+					s.value = math.Float64frombits(v)
+				} else {
+					s.timestampMS = int64(v)
+				}
+			})
+		})
+		series = append(series, s)
+	})
+	return series
+}
+
+func forEachField(t *testing.T, b []byte, f func(protowire.Number, []byte, uint64)) {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		assert.True(t, n > 0)
+		b = b[n:]
+		switch typ {
+		case protowire.BytesType:
+			v, n := protowire.ConsumeBytes(b)
+			f(num, v, 0)
+			b = b[n:]
+		case protowire.Fixed64Type:
+			v, n := protowire.ConsumeFixed64(b)
+			f(num, nil, v)
+			b = b[n:]
+		case protowire.VarintType:
+			v, n := protowire.ConsumeVarint(b)
+			f(num, nil, v)
+			b = b[n:]
+		default:
+			t.Fatalf("unexpected wire type %d", typ)
+		}
+	}
+}
+
+func TestPusher_PushToGateway(t *testing.T) {
+	server, requests := newPushServer(t, http.StatusOK)
+	p := &pusher{client: server.Client(), username: "user", password: "secret"}
+
+	assert.NoError(t, p.pushToGateway(server.URL+"/", defaultPushJob, "host-1", goldenFamilies()))
+
+	assert.Len(t, *requests, 1)
+	request := (*requests)[0]
+	assert.Equal(t, http.MethodPut, request.method)
+	assert.Equal(t, "/metrics/job/network_interface_checks/instance/host-1", request.path)
+	assert.Equal(t, "text/plain; version=0.0.4", request.header.Get("Content-Type"))
+	username, password, ok := (&http.Request{Header: request.header}).BasicAuth()
+	assert.True(t, ok)
+	assert.Equal(t, "user", username)
+	assert.Equal(t, "secret", password)
+	assert.Contains(t, string(request.body), `bytes_recv{interface="eno2",region="us west,2"} 1000`+"\n")
+	assert.NotContains(t, string(request.body), "1639666815123")
+}
+
+func TestGroupingKeyPath(t *testing.T) {
+	assert.Equal(t, "/instance/host-1", groupingKeyPath(instanceLabel, "host-1"))
+	assert.Equal(t, "/instance/host%201", groupingKeyPath(instanceLabel, "host 1"))
+	assert.Equal(t, "/instance@base64/aG9zdC8x", groupingKeyPath(instanceLabel, "host/1"))
+	assert.Equal(t, "/job@base64/", groupingKeyPath(jobLabel, ""))
+}
+
+func TestPusher_PushRemoteWrite(t *testing.T) {
+	server, requests := newPushServer(t, http.StatusNoContent)
+	p := &pusher{client: server.Client(), bearerToken: "token"}
+
+	families := goldenFamilies()
+	families[2].Metric[0].TimestampMs = nil
+	assert.NoError(t, p.pushRemoteWrite(server.URL+"/api/v1/write", defaultPushJob, "host-1", families, 1700000000000))
+
+	assert.Len(t, *requests, 1)
+	request := (*requests)[0]
+	assert.Equal(t, http.MethodPost, request.method)
+	assert.Equal(t, "/api/v1/write", request.path)
+	assert.Equal(t, "snappy", request.header.Get("Content-Encoding"))
+	assert.Equal(t, "application/x-protobuf", request.header.Get("Content-Type"))
+	assert.Equal(t, "0.1.0", request.header.Get("X-Prometheus-Remote-Write-Version"))
+	assert.Equal(t, "Bearer token", request.header.Get("Authorization"))
+
+	series := decodeWriteRequest(t, request.body)
+	count := 0
+	for _, family := range families {
+		count += len(family.Metric)
+	}
+	assert.Len(t, series, count)
+	assert.Equal(t, writeSeries{
+		labels:      []string{"__name__=bytes_recv_rate", "instance=host-1", "interface=eno2", "job=network_interface_checks", "region=us west,2"},
+		value:       12.5,
+		timestampMS: 1639666815123,
+	}, series[1])
+	assert.Equal(t, writeSeries{
+		labels:      []string{"__name__=interfaces_folded", "instance=host-1", "job=network_interface_checks", "region=us west,2"},
+		value:       2,
+		timestampMS: 1700000000000,
+	}, series[6])
+}
+
+func TestPusher_Retries(t *testing.T) {
+	defer func(backoff time.Duration) { pushBackoff = backoff }(pushBackoff)
+	pushBackoff = time.Millisecond
+
+	server, requests := newPushServer(t, http.StatusServiceUnavailable, http.StatusTooManyRequests, http.StatusOK)
+	p := &pusher{client: server.Client(), retries: 3}
+	assert.NoError(t, p.send(http.MethodPut, server.URL, nil, nil))
+	assert.Len(t, *requests, 3)
+
+	// retries exhausted
+	server, requests = newPushServer(t, http.StatusBadGateway)
+	p = &pusher{client: server.Client(), retries: 2}
+	err := p.send(http.MethodPut, server.URL, nil, nil)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "502 Bad Gateway")
+	assert.Len(t, *requests, 3)
+
+	// client errors are not retried
+	server, requests = newPushServer(t, http.StatusBadRequest)
+	p = &pusher{client: server.Client(), retries: 3}
+	assert.Error(t, p.send(http.MethodPut, server.URL, nil, nil))
+	assert.Len(t, *requests, 1)
+
+	// connection errors are retried
+	server, _ = newPushServer(t, http.StatusOK)
+	server.Close()
+	p = &pusher{client: server.Client(), retries: 1}
+	assert.Error(t, p.send(http.MethodPut, server.URL, nil, nil))
+
+	// no retry past the deadline
+	pushBackoff = time.Hour
+	server, requests = newPushServer(t, http.StatusServiceUnavailable, http.StatusOK)
+	p = &pusher{client: server.Client(), retries: 3, deadline: time.Now().Add(time.Minute)}
+	err = p.send(http.MethodPut, server.URL, nil, nil)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "503 Service Unavailable")
+	assert.Contains(t, err.Error(), "no retry before the push timeout")
+	assert.Len(t, *requests, 1)
+
+	// requests are canceled at the deadline
+	p = &pusher{client: server.Client(), deadline: time.Now().Add(-time.Second)}
+	assert.Error(t, p.send(http.MethodPut, server.URL, nil, nil))
+}
+
+func TestValidatePushURL(t *testing.T) {
+	assert.NoError(t, validatePushURL("pushgateway-url", "http://pushgateway:9091"))
+	assert.NoError(t, validatePushURL("remote-write-url", "https://prometheus/api/v1/write"))
+	assert.Error(t, validatePushURL("pushgateway-url", "pushgateway:9091"))
+	assert.Error(t, validatePushURL("pushgateway-url", "ftp://pushgateway"))
+	assert.Error(t, validatePushURL("pushgateway-url", "http://"))
+}
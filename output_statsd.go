@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"strings"
+
+	dto "github.com/prometheus/client_model/go"
+)
+
+// statsdMaxPacketSize keeps the UDP packets under the 1500 bytes Ethernet MTU, once the IP and UDP headers are added.
+const statsdMaxPacketSize = 1432
+
+var statsdTagReplacer = strings.NewReplacer(",", "_", "|", "_", "#", "_", "\n", "_")
+
+// validateStatsDAddress checks that the DogStatsD address is in host:port format.
+func validateStatsDAddress(address string) error {
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		return fmt.Errorf("--statsd-address must be in host:port format: %v", err)
+	}
+	return nil
+}
+
+// encodeDogStatsD returns the DogStatsD datagrams of the families: counters are sent as counts of their increase
+// since the previous run, and are skipped when there is no previous value, except the *_lifetime counters which are
+// sent as gauges of their value since they span the counter resets. The other families are sent as gauges.
+// Labels are sent as tags, e.g. "interface:eno1". The Sumo Logic "host_net" family is skipped since it duplicates the
+// counters, and values that can't be represented (NaN, Inf) are skipped.
+func encodeDogStatsD(families []*dto.MetricFamily, deltas map[*dto.Metric]float64) []string {
+	var datagrams []string
+	for _, family := range families {
+		if family.GetName() == sumoFamilyName {
+			continue
+		}
+		for _, m := range family.Metric {
+			value, metricType := getMetricValue(m), "g"
+			if family.GetType() == dto.MetricType_COUNTER && !strings.HasSuffix(family.GetName(), lifetimeFamilySuffix) {
+				delta, ok := deltas[m]
+				if !ok {
+					continue
+				}
+				value, metricType = delta, "c"
+			}
+			if math.IsNaN(value) || math.IsInf(value, 0) {
+				continue
+			}
+
+			datagram := family.GetName() + ":" + formatValue(value) + "|" + metricType
+			tags := make([]string, 0, len(m.Label))
+			for _, label := range m.Label {
+				tags = append(tags, statsdTagReplacer.Replace(label.GetName()+":"+label.GetValue()))
+			}
+			if len(tags) > 0 {
+				datagram += "|#" + strings.Join(tags, ",")
+			}
+			datagrams = append(datagrams, datagram)
+		}
+	}
+	return datagrams
+}
+
+// batchDatagrams joins the datagrams with newlines into packets of at most maxSize bytes. A datagram larger than
+// maxSize is sent alone.
+func batchDatagrams(datagrams []string, maxSize int) [][]byte {
+	var packets [][]byte
+	var packet []byte
+	for _, datagram := range datagrams {
+		if len(packet) > 0 && len(packet)+1+len(datagram) > maxSize {
+			packets = append(packets, packet)
+			packet = nil
+		}
+		if len(packet) > 0 {
+			packet = append(packet, '\n')
+		}
+		packet = append(packet, datagram...)
+	}
+	if len(packet) > 0 {
+		packets = append(packets, packet)
+	}
+	return packets
+}
+
+// sendDogStatsD sends the families to the DogStatsD server listening on the UDP address.
+func sendDogStatsD(address string, families []*dto.MetricFamily, deltas map[*dto.Metric]float64) error {
+	conn, err := net.Dial("udp", address)
+	if err != nil {
+		return fmt.Errorf("error connecting to DogStatsD %s: %v", address, err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	for _, packet := range batchDatagrams(encodeDogStatsD(families, deltas), statsdMaxPacketSize) {
+		if _, err := conn.Write(packet); err != nil {
+			return fmt.Errorf("error sending metrics to DogStatsD %s: %v", address, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncodeDogStatsD(t *testing.T) {
+	tmpFile := filepath.Join(t.TempDir(), uuid.New().String()+".json")
+	collector, err := NewCollector(CollectorOptions{Sum: true, SumoLogic: true, StateFile: tmpFile, MaxRateIntervalSeconds: 60})
+	assert.NoError(t, err)
+	families, err := collector.Collect(GetNetStatsMock1)
+	assert.NoError(t, err)
+
+	// no previous value, no count
+	datagrams := encodeDogStatsD(families, collector.deltas)
+	assert.Empty(t, datagrams)
+
+	time.Sleep(10 * time.Millisecond)
+	collector, err = NewCollector(CollectorOptions{
+		Sum:                    true,
+		SumoLogic:              true,
+		StateFile:              tmpFile,
+		MaxRateIntervalSeconds: 60,
+		ExtraLabels:            map[string]string{"region": "us west,2"},
+	})
+	assert.NoError(t, err)
+	families, err = collector.Collect(GetNetStatsMock2)
+	assert.NoError(t, err)
+
+	datagrams = encodeDogStatsD(families, collector.deltas)
+	assert.Contains(t, datagrams, "bytes_sent:10000000|c|#interface:eno1,region:us west_2")
+	assert.Contains(t, datagrams, "bytes_sent:20000000|c|#interface:all,region:us west_2")
+	assert.Contains(t, datagrams, "err_in:6|c|#interface:eno1,region:us west_2")
+	assert.Contains(t, datagrams, "err_in:14|c|#interface:all,region:us west_2")
+	for _, datagram := range datagrams {
+		assert.False(t, strings.HasPrefix(datagram, sumoFamilyName), datagram)
+		if strings.HasPrefix(datagram, "err_in_rate:") {
+			assert.Contains(t, datagram, "|g|#interface:")
+		}
+	}
+}
+
+func TestEncodeDogStatsD_LifetimeCounters(t *testing.T) {
+	tmpFile := filepath.Join(t.TempDir(), uuid.New().String()+".json")
+	collector, err := NewCollector(CollectorOptions{Sum: true, StateFile: tmpFile, MaxRateIntervalSeconds: 60,
+		LifetimeCounters: true})
+	assert.NoError(t, err)
+	families, err := collector.Collect(GetNetStatsMock1)
+	assert.NoError(t, err)
+
+	// the lifetime counters are gauges, sent without previous value
+	datagrams := encodeDogStatsD(families, collector.deltas)
+	assert.Contains(t, datagrams, "bytes_sent_lifetime:12345676|g|#interface:eno1")
+	for _, datagram := range datagrams {
+		assert.False(t, strings.HasPrefix(datagram, "bytes_sent:"), datagram)
+	}
+}
+
+func TestBatchDatagrams(t *testing.T) {
+	packets := batchDatagrams([]string{"a:1|c", "b:2|c", "c:3|g", strings.Repeat("d", 20), "e:5|g"}, 12)
+	assert.Equal(t, []string{"a:1|c\nb:2|c", "c:3|g", strings.Repeat("d", 20), "e:5|g"}, packetStrings(packets))
+
+	assert.Empty(t, batchDatagrams(nil, statsdMaxPacketSize))
+}
+
+func packetStrings(packets [][]byte) []string {
+	strs := make([]string, 0, len(packets))
+	for _, packet := range packets {
+		strs = append(strs, string(packet))
+	}
+	return strs
+}
+
+func TestSendDogStatsD(t *testing.T) {
+	listener, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer func() { _ = listener.Close() }()
+
+	families := goldenFamilies()
+	deltas := map[*dto.Metric]float64{}
+	for _, family := range families {
+		if family.GetType() == dto.MetricType_COUNTER {
+			for _, m := range family.Metric {
+				deltas[m] = getMetricValue(m) / 4
+			}
+		}
+	}
+	assert.NoError(t, sendDogStatsD(listener.LocalAddr().String(), families, deltas))
+
+	var received []string
+	buf := make([]byte, 65536)
+	assert.NoError(t, listener.SetReadDeadline(time.Now().Add(time.Second)))
+	for len(received) < len(encodeDogStatsD(families, deltas)) {
+		n, _, err := listener.ReadFrom(buf)
+		if !assert.NoError(t, err) {
+			break
+		}
+		assert.LessOrEqual(t, n, statsdMaxPacketSize)
+		received = append(received, strings.Split(string(buf[:n]), "\n")...)
+	}
+	assert.Equal(t, encodeDogStatsD(families, deltas), received)
+	assert.Contains(t, received, "bytes_recv:250|c|#interface:eno2,region:us west_2")
+	assert.Contains(t, received, "bytes_recv_rate:12.5|g|#interface:eno2,region:us west_2")
+
+	assert.Error(t, sendDogStatsD("localhost", families, deltas))
+}
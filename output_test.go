@@ -0,0 +1,139 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/sensu/sensu-plugin-sdk/sensu"
+	"github.com/stretchr/testify/assert"
+)
+
+var updateGolden = flag.Bool("update", false, "update the golden files of the output format tests")
+
+// goldenFamilies returns unsorted families covering counters, rates, sums, the Sumo Logic family, a family without
+// interface, extra labels, names that need escaping and values that can't be represented in every format.
+func goldenFamilies() []*dto.MetricFamily {
+	timestampMS := int64(1639666815123)
+	region, regionValue := "region", "us west,2"
+
+	rates := newMetricFamily("bytes_recv_rate", "bytes received per second", dto.MetricType_GAUGE)
+	newGaugeMetric(rates, sumInterface, 1262.5, timestampMS)
+	newGaugeMetric(rates, "eno2", 12.5, timestampMS)
+	newGaugeMetric(rates, "eno1", 1250, timestampMS)
+	newGaugeMetric(rates, "br/0.100", math.NaN(), timestampMS)
+
+	counters := newMetricFamily("bytes_recv", "bytes received", dto.MetricType_COUNTER)
+	newCounterMetric(counters, sumInterface, 10858546915, timestampMS)
+	newCounterMetric(counters, "eno2", 1000, timestampMS)
+	newCounterMetric(counters, "eno1", 10858544415, timestampMS)
+	newCounterMetric(counters, "br/0.100", 1500, timestampMS)
+
+	errors := newMetricFamily("err_in", "inbound errors", dto.MetricType_COUNTER)
+	newCounterMetric(errors, "eno1", 123, timestampMS)
+	newCounterMetric(errors, "eno2", 0, timestampMS)
+
+	sumo := newMetricFamily(sumoFamilyName, "SumoLogic Compatibility", dto.MetricType_COUNTER)
+	newSumoCounterMetric(sumo, "err_in", "eno1", 123, timestampMS)
+	newSumoCounterMetric(sumo, "bytes_recv", "eno1", 10858544415, timestampMS)
+
+	folded := newMetricFamily(foldedFamilyName, metricHelp[foldedFamilyName], dto.MetricType_GAUGE)
+	value := float64(2)
+	folded.Metric = append(folded.Metric, &dto.Metric{Gauge: &dto.Gauge{Value: &value}, TimestampMs: &timestampMS})
+
+	families := []*dto.MetricFamily{rates, sumo, folded, counters, errors}
+	for _, family := range families {
+		for _, m := range family.Metric {
+			m.Label = append(m.Label, &dto.LabelPair{Name: &region, Value: &regionValue})
+		}
+	}
+
+	return families
+}
+
+// assertGolden compares the output with the content of the golden file, or updates the golden file when the
+// tests are run with -update.
+func assertGolden(t *testing.T, name string, output []byte) {
+	golden := filepath.Join("testdata", name)
+	if *updateGolden {
+		assert.NoError(t, os.WriteFile(golden, output, 0644))
+	}
+	expected, err := os.ReadFile(golden)
+	assert.NoError(t, err)
+	assert.Equal(t, string(expected), string(output))
+}
+
+func TestEncodePrometheusText(t *testing.T) {
+	family := newMetricFamily("err_in", "inbound errors", dto.MetricType_COUNTER)
+	newCounterMetric(family, "eno1", 7, 1639666815123)
+
+	var buf bytes.Buffer
+	err := encodePrometheusText(&buf, []Alert{}, []*dto.MetricFamily{family})
+	assert.NoError(t, err)
+	assert.Equal(t, "# HELP err_in inbound errors\n# TYPE err_in counter\nerr_in{interface=\"eno1\"} 7 1639666815123\n", buf.String())
+
+	buf.Reset()
+	alerts := []Alert{{Status: sensu.CheckStateCritical, Message: "err_in on eno1 is 7 (>= 5)"}}
+	err = encodePrometheusText(&buf, alerts, []*dto.MetricFamily{family})
+	assert.NoError(t, err)
+	assert.Equal(t, "# CRITICAL: err_in on eno1 is 7 (>= 5)\n# HELP err_in inbound errors\n# TYPE err_in counter\n"+
+		"err_in{interface=\"eno1\"} 7 1639666815123\n", buf.String())
+}
+
+func TestWriteMetrics(t *testing.T) {
+	family := newMetricFamily("err_in", "inbound errors", dto.MetricType_COUNTER)
+	newCounterMetric(family, "eno1", 7, 1639666815123)
+	alert := Alert{Status: sensu.CheckStateCritical, Rule: "threshold:err_in", Interface: "eno1",
+		Message: "err_in on eno1 is 7 (>= 5)"}
+
+	// alerts kept in the state file while no alert rule is configured
+	collector := &MetricCollector{alerts: []Alert{alert}}
+	var buf bytes.Buffer
+	assert.NoError(t, writeMetrics(&buf, outputFormatPrometheus, "net", "", []*dto.MetricFamily{family}, collector, nil))
+	assert.NotContains(t, buf.String(), "# CRITICAL")
+
+	critical, err := parseThresholds(nil, map[string]string{"err_in": "5"})
+	assert.NoError(t, err)
+	collector.thresholds = critical
+	buf.Reset()
+	assert.NoError(t, writeMetrics(&buf, outputFormatPrometheus, "net", "", []*dto.MetricFamily{family}, collector, nil))
+	assert.True(t, strings.HasPrefix(buf.String(), "# CRITICAL: err_in on eno1 is 7 (>= 5)\n"))
+
+	buf.Reset()
+	assert.NoError(t, writeMetrics(&buf, outputFormatNagios, "net", "", []*dto.MetricFamily{family}, collector, nil))
+	assert.True(t, strings.HasPrefix(buf.String(), "NET CRITICAL - "), buf.String())
+}
+
+func TestValidOutputFormat(t *testing.T) {
+	for _, format := range outputFormats {
+		assert.True(t, validOutputFormat(format))
+	}
+	assert.False(t, validOutputFormat("xml"))
+}
+
+func TestSortFamilies(t *testing.T) {
+	families := sortFamilies(goldenFamilies())
+	names := make([]string, 0, len(families))
+	for _, family := range families {
+		names = append(names, family.GetName())
+	}
+	assert.Equal(t, []string{"bytes_recv", "bytes_recv_rate", "err_in", sumoFamilyName, foldedFamilyName}, names)
+
+	interfaces := make([]string, 0)
+	for _, m := range families[0].Metric {
+		interfaces = append(interfaces, metricInterface(m))
+	}
+	assert.Equal(t, []string{"br/0.100", "eno1", "eno2", sumInterface}, interfaces)
+
+	fields := make([]string, 0)
+	for _, m := range families[3].Metric {
+		field, _ := getLabelValue(m, fieldLabel)
+		fields = append(fields, field)
+	}
+	assert.Equal(t, []string{"bytes_recv", "err_in"}, fields)
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+)
+
+const (
+	textfileName           = "network_interface_checks.prom"
+	lastRunTimestampFamily = pluginNamespace + "last_run_timestamp"
+	lastRunTimestampHelp   = "unix time in seconds of the last run of the checks"
+)
+
+// validateTextfileDir checks that the node_exporter textfile collector directory exists.
+func validateTextfileDir(dir string) error {
+	info, err := os.Stat(dir)
+	if err != nil || !info.IsDir() {
+		return fmt.Errorf("--textfile-dir %s must be an existing directory", dir)
+	}
+	return nil
+}
+
+// writeTextfile writes the families to the node_exporter textfile collector directory, followed by the last run
+// timestamp gauge. The file is written to a temporary file renamed once complete, so that node_exporter never reads
+// a partial file.
+func writeTextfile(dir string, families []*dto.MetricFamily, now time.Time) error {
+	tmpFile, err := ioutil.TempFile(dir, "."+textfileName+".*")
+	if err != nil {
+		return fmt.Errorf("error creating textfile in %s: %v", dir, err)
+	}
+	defer func() { _ = os.Remove(tmpFile.Name()) }()
+
+	lastRun := newMetricFamily(lastRunTimestampFamily, lastRunTimestampHelp, dto.MetricType_GAUGE)
+	value := float64(now.UnixNano()) / 1e9
+	lastRun.Metric = append(lastRun.Metric, &dto.Metric{Gauge: &dto.Gauge{Value: &value}})
+
+	err = encodePrometheusText(tmpFile, nil, append(withoutTimestamps(families), lastRun))
+	if closeErr := tmpFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("error writing textfile %s: %v", tmpFile.Name(), err)
+	}
+
+	if err := os.Chmod(tmpFile.Name(), 0644); err != nil {
+		return fmt.Errorf("error writing textfile %s: %v", tmpFile.Name(), err)
+	}
+	path := filepath.Join(dir, textfileName)
+	if err := os.Rename(tmpFile.Name(), path); err != nil {
+		return fmt.Errorf("error renaming textfile to %s: %v", path, err)
+	}
+
+	return nil
+}
+
+// withoutTimestamps returns copies of the families without sample timestamps, which the textfile collector rejects.
+func withoutTimestamps(families []*dto.MetricFamily) []*dto.MetricFamily {
+	copies := make([]*dto.MetricFamily, 0, len(families))
+	for _, family := range families {
+		familyCopy := *family
+		familyCopy.Metric = make([]*dto.Metric, 0, len(family.Metric))
+		for _, m := range family.Metric {
+			metricCopy := *m
+			metricCopy.TimestampMs = nil
+			familyCopy.Metric = append(familyCopy.Metric, &metricCopy)
+		}
+		copies = append(copies, &familyCopy)
+	}
+	return copies
+}
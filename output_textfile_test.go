@@ -0,0 +1,54 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteTextfile(t *testing.T) {
+	dir := t.TempDir()
+	families := goldenFamilies()
+	now := time.Unix(1700000000, 500000000)
+
+	assert.NoError(t, writeTextfile(dir, families, now))
+
+	content, err := ioutil.ReadFile(filepath.Join(dir, textfileName))
+	assert.NoError(t, err)
+	assert.Contains(t, string(content), `bytes_recv{interface="eno2",region="us west,2"} 1000`+"\n")
+	assert.Contains(t, string(content), "# TYPE network_interface_checks_last_run_timestamp gauge\n"+
+		"network_interface_checks_last_run_timestamp 1.7000000005e+09\n")
+
+	// the collected families keep their timestamps
+	assert.NotNil(t, families[0].Metric[0].TimestampMs)
+
+	// no temporary file left behind
+	entries, err := ioutil.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+	info, err := os.Stat(filepath.Join(dir, textfileName))
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0644), info.Mode().Perm())
+
+	// overwritten on next run
+	assert.NoError(t, writeTextfile(dir, families[:1], now.Add(time.Minute)))
+	content, err = ioutil.ReadFile(filepath.Join(dir, textfileName))
+	assert.NoError(t, err)
+	assert.Contains(t, string(content), "network_interface_checks_last_run_timestamp 1.7000000605e+09\n")
+
+	assert.Error(t, writeTextfile(filepath.Join(dir, "missing"), families, now))
+}
+
+func TestValidateTextfileDir(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, validateTextfileDir(dir))
+	assert.Error(t, validateTextfileDir(filepath.Join(dir, "missing")))
+
+	file := filepath.Join(dir, "file")
+	assert.NoError(t, ioutil.WriteFile(file, []byte{}, 0644))
+	assert.Error(t, validateTextfileDir(file))
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/sensu/network-interface-checks/metric"
+	v2 "github.com/sensu/sensu-go/api/core/v2"
+	"github.com/sensu/sensu-plugin-sdk/sensu"
+)
+
+const (
+	serveCommand  = "serve"
+	defaultListen = ":9835"
+
+	shutdownTimeout = 5 * time.Second
+)
+
+// exporterCollector is a prometheus.Collector running the MetricCollector on each scrape. Rates are computed from
+// the counters of the previous scrape, kept in memory. The state of the interfaces missing from a scrape is dropped.
+type exporterCollector struct {
+	mu             sync.Mutex
+	collector      *MetricCollector
+	netStatsGetter func(*selector) (NetStats, error)
+	errorDesc      *prometheus.Desc
+}
+
+func newExporterCollector(collector *MetricCollector, netStatsGetter func(*selector) (NetStats, error)) *exporterCollector {
+	collector.memoryState = metric.New()
+	return &exporterCollector{
+		collector:      collector,
+		netStatsGetter: netStatsGetter,
+		errorDesc:      prometheus.NewDesc(pluginNamespace+"collect_error", "error collecting network interface metrics", nil, nil),
+	}
+}
+
+// Describe sends no descriptor, the families depend on the interfaces present at scrape time which makes the
+// collector unchecked.
+func (e *exporterCollector) Describe(_ chan<- *prometheus.Desc) {
+}
+
+func (e *exporterCollector) Collect(ch chan<- prometheus.Metric) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	families, err := e.collector.Collect(e.netStatsGetter)
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(e.errorDesc, err)
+		return
+	}
+	e.collector.memoryState.Prune()
+
+	for _, family := range families {
+		for _, m := range family.Metric {
+			ch <- newConstMetric(family, m)
+		}
+	}
+}
+
+// newConstMetric converts a metric of the family to a prometheus.Metric. The timestamp is dropped since the samples
+// are taken at scrape time.
+func newConstMetric(family *dto.MetricFamily, m *dto.Metric) prometheus.Metric {
+	labelNames := make([]string, 0, len(m.Label))
+	labelValues := make([]string, 0, len(m.Label))
+	for _, label := range m.Label {
+		labelNames = append(labelNames, label.GetName())
+		labelValues = append(labelValues, label.GetValue())
+	}
+	desc := prometheus.NewDesc(family.GetName(), family.GetHelp(), labelNames, nil)
+
+	valueType := prometheus.UntypedValue
+	switch family.GetType() {
+	case dto.MetricType_COUNTER:
+		valueType = prometheus.CounterValue
+	case dto.MetricType_GAUGE:
+		valueType = prometheus.GaugeValue
+	}
+
+	constMetric, err := prometheus.NewConstMetric(desc, valueType, getMetricValue(m), labelValues...)
+	if err != nil {
+		return prometheus.NewInvalidMetric(desc, err)
+	}
+	return constMetric
+}
+
+// newServeMux returns the handler of the exporter endpoints, /metrics and /healthz.
+func newServeMux(collector *MetricCollector, netStatsGetter func(*selector) (NetStats, error)) (*http.ServeMux, error) {
+	registry := prometheus.NewRegistry()
+	if err := registry.Register(newExporterCollector(collector, netStatsGetter)); err != nil {
+		return nil, err
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{ErrorHandling: promhttp.ContinueOnError}))
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		_, _ = fmt.Fprintln(w, "ok")
+	})
+
+	return mux, nil
+}
+
+// serve runs the exporter on the listen address until the context is done, then waits for the in-flight scrapes to
+// complete.
+func serve(ctx context.Context, listen string, handler http.Handler) error {
+	server := &http.Server{Addr: listen, Handler: handler}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+	if err := <-errCh; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// serveArgs returns the arguments without the serve subcommand when it's the first argument, which runs the
+// exporter instead of the check.
+func serveArgs(args []string) ([]string, bool) {
+	if len(args) < 2 || args[1] != serveCommand {
+		return args, false
+	}
+	return append(args[:1:1], args[2:]...), true
+}
+
+// newServeCheck returns the plugin running the exporter, which accepts the flags of the check. It exits with the
+// status of the exporter like the check does. The state is kept in memory, so --state-file isn't required.
+func newServeCheck() *sensu.GoCheck {
+	config := plugin.PluginConfig
+	config.Name += " " + serveCommand
+	config.Short = "Run as a Prometheus exporter serving /metrics and /healthz on --listen"
+	return sensu.NewGoCheck(&config, options, validateArgs, executeServe, false)
+}
+
+func executeServe(event *v2.Event) (int, error) {
+	collector, err := newMetricCollector(event)
+	if err != nil {
+		fmt.Printf("Error executing %s: %v\n", plugin.Name, err)
+		return sensu.CheckStateCritical, nil
+	}
+	mux, err := newServeMux(collector, GetNetStats)
+	if err != nil {
+		fmt.Printf("Error executing %s: %v\n", plugin.Name, err)
+		return sensu.CheckStateCritical, nil
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+
+	log.Printf("serving metrics on %s/metrics", plugin.Listen)
+	if err := serve(ctx, plugin.Listen, mux); err != nil {
+		fmt.Printf("Error executing %s: %v\n", plugin.Name, err)
+		return sensu.CheckStateCritical, nil
+	}
+	return sensu.CheckStateOK, nil
+}
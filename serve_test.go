@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+)
+
+func scrape(t *testing.T, url string) (int, string) {
+	resp, err := http.Get(url)
+	assert.NoError(t, err)
+	defer func() { _ = resp.Body.Close() }()
+	body, err := ioutil.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	return resp.StatusCode, string(body)
+}
+
+func TestServeMux_Metrics(t *testing.T) {
+	collector, err := NewCollector(CollectorOptions{Sum: true, MaxRateIntervalSeconds: 60})
+	assert.NoError(t, err)
+	getters := []func(*selector) (NetStats, error){GetNetStatsMock1, GetNetStatsMock2}
+	scrapes := 0
+	mux, err := newServeMux(collector, func(s *selector) (NetStats, error) {
+		scrapes++
+		return getters[(scrapes-1)%len(getters)](s)
+	})
+	assert.NoError(t, err)
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	status, body := scrape(t, server.URL+"/metrics")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Contains(t, body, "# TYPE bytes_sent counter")
+	assert.Contains(t, body, `bytes_sent{interface="eno1"} 1.2345676e+07`)
+	assert.Contains(t, body, `err_in{interface="all"} 6`)
+	assert.NotContains(t, body, "_rate")
+
+	// small sleep to ensure second scrape has different timestamp
+	time.Sleep(10 * time.Millisecond)
+
+	status, body = scrape(t, server.URL+"/metrics")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Contains(t, body, "# TYPE bytes_sent_rate gauge")
+	assert.Contains(t, body, `err_in_rate{interface="eno1"}`)
+	assert.Contains(t, body, `err_in_rate{interface="all"}`)
+	for _, line := range strings.Split(strings.TrimSpace(body), "\n") {
+		if !strings.HasPrefix(line, "#") {
+			assert.Len(t, strings.Fields(line), 2, "no timestamp expected in %q", line)
+		}
+	}
+	assert.Equal(t, 2, scrapes)
+}
+
+func TestServeMux_PruneState(t *testing.T) {
+	collector, err := NewCollector(CollectorOptions{MaxRateIntervalSeconds: 60})
+	assert.NoError(t, err)
+	stats := []NetStats{
+		{"bytes_recv": {"eno1": 100, "veth1": 10}},
+		{"bytes_recv": {"eno1": 200}},
+	}
+	scrapes := 0
+	mux, err := newServeMux(collector, func(_ *selector) (NetStats, error) {
+		scrapes++
+		return stats[scrapes-1], nil
+	})
+	assert.NoError(t, err)
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	family := newMetricFamily("bytes_recv", metricHelp["bytes_recv"], dto.MetricType_COUNTER)
+	scrape(t, server.URL+"/metrics")
+	found, _, _ := collector.memoryState.GetMetric(family, newCounterMetric(family, "veth1", 0, 0))
+	assert.True(t, found)
+
+	// the state of the removed interface is dropped
+	scrape(t, server.URL+"/metrics")
+	found, _, _ = collector.memoryState.GetMetric(family, newCounterMetric(family, "veth1", 0, 0))
+	assert.False(t, found)
+	found, _, _ = collector.memoryState.GetMetric(family, newCounterMetric(family, "eno1", 0, 0))
+	assert.True(t, found)
+}
+
+func TestServeMux_CollectError(t *testing.T) {
+	collector, err := NewCollector(CollectorOptions{MaxRateIntervalSeconds: 60})
+	assert.NoError(t, err)
+	mux, err := newServeMux(collector, func(_ *selector) (NetStats, error) {
+		return nil, assert.AnError
+	})
+	assert.NoError(t, err)
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	// the scrape fails so that the target is reported down
+	status, body := scrape(t, server.URL+"/metrics")
+	assert.Equal(t, http.StatusInternalServerError, status)
+	assert.Contains(t, body, assert.AnError.Error())
+	status, body = scrape(t, server.URL+"/healthz")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "ok\n", body)
+}
+
+func TestServe_Shutdown(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	listen := listener.Addr().String()
+	assert.NoError(t, listener.Close())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- serve(ctx, listen, http.NotFoundHandler())
+	}()
+
+	assert.Eventually(t, func() bool {
+		conn, err := net.Dial("tcp", listen)
+		if err != nil {
+			return false
+		}
+		_ = conn.Close()
+		return true
+	}, time.Second, 10*time.Millisecond)
+
+	cancel()
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(shutdownTimeout):
+		t.Fatal("serve did not return after the context was done")
+	}
+
+	// the listen address is already in use
+	listener, err = net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer func() { _ = listener.Close() }()
+	assert.Error(t, serve(context.Background(), listener.Addr().String(), http.NotFoundHandler()))
+}
+
+func TestServeArgs(t *testing.T) {
+	args, ok := serveArgs([]string{"network-interface-checks", serveCommand, "--listen", ":9100"})
+	assert.True(t, ok)
+	assert.Equal(t, []string{"network-interface-checks", "--listen", ":9100"}, args)
+
+	// the check
+	args, ok = serveArgs([]string{"network-interface-checks", "--sum"})
+	assert.False(t, ok)
+	assert.Equal(t, []string{"network-interface-checks", "--sum"}, args)
+	_, ok = serveArgs([]string{"network-interface-checks"})
+	assert.False(t, ok)
+
+	// the subcommand must be the first argument
+	_, ok = serveArgs([]string{"network-interface-checks", "--sum", serveCommand})
+	assert.False(t, ok)
+}